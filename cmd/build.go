@@ -1,14 +1,27 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	"github.com/lade-io/jet/pack"
 	"github.com/spf13/cobra"
 )
 
+type buildOptions struct {
+	configOptions
+	imageName  string
+	progress   string
+	sbom       string
+	sbomOutput string
+}
+
 var buildCmd = func() *cobra.Command {
-	var imageName string
+	opts := &buildOptions{}
 	cmd := &cobra.Command{
 		Use:   "build <path>",
 		Short: "Build a Docker image from source",
@@ -18,22 +31,95 @@ var buildCmd = func() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if imageName == "" {
-				imageName = filepath.Base(workDir)
+			if opts.imageName == "" {
+				opts.imageName = filepath.Base(workDir)
 			}
-			return buildRun(imageName, workDir)
+			return buildRun(workDir, opts)
 		},
 	}
-	cmd.Flags().StringVarP(&imageName, "name", "n", "", "Image Name")
+	cmd.Flags().StringVarP(&opts.imageName, "name", "n", "", "Image Name")
+	cmd.Flags().StringVar(&opts.progress, "progress", "auto", "Progress output (auto, tty, plain, json)")
+	cmd.Flags().StringVar(&opts.sbom, "sbom", "", "Write SBOM document (spdx, cyclonedx)")
+	cmd.Flags().StringVar(&opts.sbomOutput, "sbom-output", "", "SBOM file (default <name>.<format>.json)")
+	opts.addFlags(cmd)
 	return cmd
 }()
 
-func buildRun(imageName, workDir string) error {
-	bp, err := pack.Detect(workDir)
+func buildRun(workDir string, opts *buildOptions) error {
+	switch opts.sbom {
+	case "", pack.SBOMSPDX, pack.SBOMCycloneDX:
+	default:
+		return fmt.Errorf("Unknown SBOM format %s", opts.sbom)
+	}
+
+	events, err := newProgress(opts.progress, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	conf, err := opts.load(workDir)
+	if err != nil {
+		return err
+	}
+
+	bp, err := pack.DetectConfig(workDir, conf)
+	if err != nil {
+		return err
+	}
+
+	bp.Events = events
+	_, err = bp.BuildImage(opts.imageName)
+
+	var buildErr *pack.BuildError
+	if errors.As(err, &buildErr) {
+		buildSummary(os.Stderr, buildErr)
+	}
 	if err != nil {
 		return err
 	}
 
-	_, err = bp.BuildImage(imageName)
-	return err
+	if opts.sbom != "" {
+		return sbomWrite(bp, opts)
+	}
+	return nil
+}
+
+func buildSummary(out io.Writer, buildErr *pack.BuildError) {
+	fmt.Fprintln(out)
+	if buildErr.Step > 0 {
+		fmt.Fprintf(out, "Failed step: %d/%d %s\n", buildErr.Step, buildErr.Steps, buildErr.Instruction)
+	}
+	if buildErr.Command != "" {
+		fmt.Fprintf(out, "Command: %s\n", buildErr.Command)
+	}
+	if buildErr.ExitCode > 0 {
+		fmt.Fprintf(out, "Exit code: %d\n", buildErr.ExitCode)
+	} else {
+		fmt.Fprintf(out, "Error: %s\n", buildErr.Message)
+	}
+	if len(buildErr.Output) > 0 {
+		fmt.Fprintf(out, "Last %d lines of output:\n", len(buildErr.Output))
+		for _, line := range buildErr.Output {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+func sbomWrite(bp *pack.Buildpack, opts *buildOptions) error {
+	doc, err := bp.SBOM(opts.imageName, opts.sbom)
+	if err != nil {
+		return err
+	}
+
+	output := opts.sbomOutput
+	if output == "" {
+		output = fmt.Sprintf("%s.%s.json", filepath.Base(opts.imageName), opts.sbom)
+	}
+
+	if err = ioutil.WriteFile(output, doc, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "SBOM written to", output)
+	return nil
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lade-io/jet/pack"
+	"github.com/spf13/cobra"
+)
+
+type configOptions struct {
+	file        string
+	buildArgs   []string
+	env         []string
+	goMain      string
+	goTags      []string
+	healthcheck string
+	labels      []string
+	port        int
+	rules       string
+	singleStage bool
+	variant     string
+}
+
+func (o *configOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.file, "config", "c", "", "Config file (default jet.yml)")
+	cmd.Flags().StringArrayVar(&o.buildArgs, "build-arg", nil, "Set build-time variables")
+	cmd.Flags().StringArrayVarP(&o.env, "env", "e", nil, "Set environment variables")
+	cmd.Flags().StringArrayVarP(&o.labels, "label", "l", nil, "Set image labels")
+	cmd.Flags().IntVarP(&o.port, "port", "p", 0, "Port the app listens on (default 3000)")
+	cmd.Flags().StringVar(&o.goMain, "go-main", "", "Go main package to build (default . or cmd/*)")
+	cmd.Flags().StringSliceVar(&o.goTags, "go-tags", nil, "Go build tags")
+	cmd.Flags().StringVar(&o.healthcheck, "healthcheck", "", "HTTP path to probe for health checks")
+	cmd.Flags().StringVar(&o.rules, "rules", "", "Directory or file of rules extending the built-in ones")
+	cmd.Flags().StringVar(&o.variant, "variant", "", "PHP server variant: apache or fpm (default apache)")
+	cmd.Flags().BoolVar(&o.singleStage, "single-stage", false, "Ship build dependencies in a single stage image")
+}
+
+func (o *configOptions) load(workDir string) (*pack.Config, error) {
+	conf, err := pack.LoadConfig(workDir, o.file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arg := range o.buildArgs {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			value, ok = os.LookupEnv(key)
+			if !ok {
+				return nil, fmt.Errorf("Missing value for build argument %s", key)
+			}
+		}
+		conf.SetBuildArg(key, value)
+	}
+
+	for _, env := range o.env {
+		key, value, ok := strings.Cut(env, "=")
+		if !ok {
+			return nil, fmt.Errorf("Invalid environment variable %s", env)
+		}
+		conf.SetEnv(key, value)
+	}
+
+	for _, label := range o.labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok {
+			return nil, fmt.Errorf("Invalid label %s", label)
+		}
+		conf.SetLabel(key, value)
+	}
+
+	if o.port > 0 {
+		conf.Port = o.port
+	}
+	if o.goMain != "" {
+		conf.GoMain = o.goMain
+	}
+	if len(o.goTags) > 0 {
+		conf.GoTags = o.goTags
+	}
+	if o.healthcheck != "" {
+		conf.Healthcheck = o.healthcheck
+	}
+	if o.rules != "" {
+		if conf.Rules, err = filepath.Abs(o.rules); err != nil {
+			return nil, err
+		}
+	}
+	if o.singleStage {
+		conf.SingleStage = true
+	}
+	if o.variant != "" {
+		conf.Variant = o.variant
+	}
+	return conf, nil
+}
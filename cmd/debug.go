@@ -8,21 +8,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var debugCmd = &cobra.Command{
-	Use:   "debug <path>",
-	Short: "Print generated Dockerfile",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		workDir, err := filepath.Abs(args[0])
-		if err != nil {
-			return err
-		}
-		return debugRun(workDir)
-	},
-}
+var debugCmd = func() *cobra.Command {
+	var opts configOptions
+	cmd := &cobra.Command{
+		Use:   "debug <path>",
+		Short: "Print generated Dockerfile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			return debugRun(workDir, &opts)
+		},
+	}
+	opts.addFlags(cmd)
+	return cmd
+}()
+
+func debugRun(workDir string, opts *configOptions) error {
+	conf, err := opts.load(workDir)
+	if err != nil {
+		return err
+	}
 
-func debugRun(workDir string) error {
-	bp, err := pack.Detect(workDir)
+	bp, err := pack.DetectConfig(workDir, conf)
 	if err != nil {
 		return err
 	}
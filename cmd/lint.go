@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lade-io/jet/pack"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = func() *cobra.Command {
+	var opts configOptions
+	cmd := &cobra.Command{
+		Use:   "lint <path>",
+		Short: "Check Dockerfiles against best practices",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			return lintRun(workDir, &opts)
+		},
+	}
+	opts.addFlags(cmd)
+	return cmd
+}()
+
+func lintRun(workDir string, opts *configOptions) error {
+	count := 0
+	dockerfile := filepath.Join(workDir, "Dockerfile")
+	issues, err := pack.LintFile(dockerfile)
+	exists := err == nil
+	if exists {
+		count += len(issues)
+		lintPrint("Dockerfile", issues)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	conf, err := opts.load(workDir)
+	if err != nil {
+		return err
+	}
+
+	bp, err := pack.DetectConfig(workDir, conf)
+	if errors.Is(err, pack.ErrNoBuildpack) && exists {
+		if count > 0 {
+			return fmt.Errorf("Found %d issues", count)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	issues, err = bp.Lint()
+	if err != nil {
+		return err
+	}
+	count += len(issues)
+	lintPrint("Generated Dockerfile", issues)
+
+	if count > 0 {
+		return fmt.Errorf("Found %d issues", count)
+	}
+	return nil
+}
+
+func lintPrint(name string, issues []*pack.Issue) {
+	for _, issue := range issues {
+		fmt.Printf("%s:%d: %s [%s] %s\n", name, issue.Line, issue.Severity, issue.Rule, issue.Message)
+	}
+}
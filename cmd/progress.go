@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lade-io/jet/pack"
+	"github.com/moby/term"
+)
+
+const (
+	ansiBold  = "\033[1m"
+	ansiClear = "\r\033[K"
+	ansiDim   = "\033[2m"
+	ansiGreen = "\033[32m"
+	ansiRed   = "\033[31m"
+	ansiReset = "\033[0m"
+)
+
+func newProgress(mode string, out io.Writer) (pack.EventHandler, error) {
+	if mode == "auto" {
+		mode = "plain"
+		if isTerminal(out) {
+			mode = "tty"
+		}
+	}
+
+	switch mode {
+	case "tty":
+		return ttyProgress(out), nil
+	case "plain":
+		return plainProgress(out), nil
+	case "json":
+		return jsonProgress(out), nil
+	}
+	return nil, fmt.Errorf("Unknown progress mode %s", mode)
+}
+
+// ttyProgress keeps one status line under each step, rewriting it in place
+// with the latest output until the step finishes or fails.
+func ttyProgress(out io.Writer) pack.EventHandler {
+	width := terminalWidth(out)
+	return func(event *pack.Event) {
+		switch event.Type {
+		case pack.EventStepStart:
+			fmt.Fprintf(out, "%s%s[%d/%d] %s%s\n", ansiClear, ansiBold, event.Step, event.Steps, event.Instruction, ansiReset)
+		case pack.EventOutput:
+			fmt.Fprintf(out, "%s%s  %s%s", ansiClear, ansiDim, truncate(event.Message, width-2), ansiReset)
+		case pack.EventStepFinish:
+			fmt.Fprintf(out, "%s%s  ✔ %s%s\n", ansiClear, ansiGreen, stepSummary(event), ansiReset)
+		case pack.EventError:
+			fmt.Fprintf(out, "%s%s  ✘ %s%s\n", ansiClear, ansiRed, event.Error, ansiReset)
+		}
+	}
+}
+
+func plainProgress(out io.Writer) pack.EventHandler {
+	return func(event *pack.Event) {
+		prefix := fmt.Sprintf("[%d/%d]", event.Step, event.Steps)
+		switch event.Type {
+		case pack.EventStepStart:
+			fmt.Fprintln(out, prefix, event.Instruction)
+		case pack.EventOutput:
+			fmt.Fprintln(out, prefix, event.Message)
+		case pack.EventStepFinish:
+			fmt.Fprintln(out, prefix, "done", stepSummary(event))
+		case pack.EventError:
+			fmt.Fprintln(out, prefix, "error", event.Error)
+		}
+	}
+}
+
+func jsonProgress(out io.Writer) pack.EventHandler {
+	encoder := json.NewEncoder(out)
+	return func(event *pack.Event) {
+		encoder.Encode(event)
+	}
+}
+
+func stepSummary(event *pack.Event) string {
+	if event.Cached {
+		return "cached"
+	}
+
+	summary := event.Duration.Round(100 * time.Millisecond).String()
+	if event.Size > 0 {
+		summary += " " + formatSize(event.Size)
+	}
+	return summary
+}
+
+func formatSize(size int64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	value := float64(size)
+	i := 0
+	for value >= 1000 && i < len(units)-1 {
+		value /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
+
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if width > 0 && len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return line
+}
+
+func terminalWidth(out io.Writer) int {
+	if file, ok := out.(*os.File); ok {
+		if size, err := term.GetWinsize(file.Fd()); err == nil && size.Width > 0 {
+			return int(size.Width)
+		}
+	}
+	return 80
+}
+
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
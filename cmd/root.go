@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"log"
 
+	"github.com/lade-io/jet/pack"
 	"github.com/spf13/cobra"
 )
 
+const (
+	ExitError      = 1
+	ExitBuildError = 2
+)
+
 var RootCmd = &cobra.Command{
 	Use:   "jet",
 	Short: "Convert source code into Docker images",
@@ -17,6 +24,14 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+func ExitCode(err error) int {
+	var buildErr *pack.BuildError
+	if errors.As(err, &buildErr) {
+		return ExitBuildError
+	}
+	return ExitError
+}
+
 func SetVersion(version string) {
 	RootCmd.Version = version
 }
@@ -31,6 +46,7 @@ func init() {
 
 	RootCmd.AddCommand(buildCmd)
 	RootCmd.AddCommand(debugCmd)
+	RootCmd.AddCommand(lintCmd)
 	RootCmd.AddCommand(versionCmd)
 }
 
@@ -3,6 +3,7 @@ module github.com/lade-io/jet
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/ake-persson/mapslice-json v0.0.0-20210720081907-22c8edf57807
 	github.com/aquasecurity/go-version v0.0.0-20210121072130-637058cfe492
 	github.com/bmatcuk/doublestar v1.3.4
@@ -12,6 +13,7 @@ require (
 	github.com/docker/libcompose v0.4.1-0.20181019154650-213509acef0f
 	github.com/google/go-github/v45 v45.2.0
 	github.com/gregjones/httpcache v0.0.0-20190212212710-3befbb6ad0cc
+	github.com/moby/term v0.0.0-20201216013528-df9cb8a40635
 	github.com/ory/dockertest/v3 v3.6.5
 	github.com/spf13/cobra v1.5.0
 	github.com/stretchr/testify v1.8.0
@@ -51,7 +53,6 @@ require (
 	github.com/moby/sys/mount v0.2.0 // indirect
 	github.com/moby/sys/mountinfo v0.4.1 // indirect
 	github.com/moby/sys/symlink v0.1.0 // indirect
-	github.com/moby/term v0.0.0-20201216013528-df9cb8a40635 // indirect
 	github.com/morikuni/aec v1.0.0 // indirect
 	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/opencontainers/image-spec v1.0.1 // indirect
@@ -9,13 +9,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/libcompose/docker/builder"
 	"github.com/docker/libcompose/docker/client"
-	"github.com/docker/libcompose/logger"
 )
 
 var (
@@ -30,26 +31,45 @@ type Pack interface {
 	Version() (string, error)
 }
 
+type versionResolver interface {
+	resolveVersion(constraint string) (string, error)
+}
+
 type Metadata struct {
-	Command  string
-	Depends  []*Depend
-	Env      map[string]string
-	Install  []string
-	Name     string
-	Packages []string
-	Path     string
-	Process  []string
-	Root     Root
-	Tools    []*Tool
-	User     string
-	Variant  string
-	Version  string
+	Args          []string
+	Bin           []string
+	BuildPackages []string
+	Command       string
+	Depends       []*Depend
+	Distro        string
+	Env           map[string]string
+	Framework     string
+	Health        string
+	Image         string
+	Install       []string
+	InstallArgs   []string
+	Keep          []string
+	Name          string
+	Packages      []string
+	Path          string
+	Port          int
+	Process       []string
+	Processes     map[string]string
+	Prune         []string
+	Release       string
+	Root          Root
+	RunEnv        map[string]string
+	Tools         []*Tool
+	User          string
+	Variant       string
+	Version       string
 }
 
 type Depend struct {
 	Name string
 	Args []string
 	List bool
+	Hook func(meta *Metadata, depend *Depend) error
 }
 
 type Root struct {
@@ -58,9 +78,12 @@ type Root struct {
 }
 
 type Tool struct {
+	Args     []string
 	Copy     map[string][]string
 	Name     string
 	Owner    string
+	Repo     string
+	Asset    string
 	Archive  string
 	Binary   bool
 	Download string
@@ -69,13 +92,43 @@ type Tool struct {
 	Hook     func(meta *Metadata, tool *Tool) error
 }
 
-func Detect(workDir string) (pack *Buildpack, err error) {
+func Detect(workDir string) (*Buildpack, error) {
+	conf, err := LoadConfig(workDir, "")
+	if err != nil {
+		return nil, err
+	}
+	return DetectConfig(workDir, conf)
+}
+
+func DetectConfig(workDir string, conf *Config) (pack *Buildpack, err error) {
+	dir := conf.Rules
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(workDir, dir)
+	}
+	rules, err := LoadRules(dir)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Variant != "" && conf.Variant != "apache" && conf.Variant != "fpm" {
+		return nil, fmt.Errorf("Unknown variant %s", conf.Variant)
+	}
+	for key := range conf.BuildArgs {
+		if !envKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("Invalid build argument %s", key)
+		}
+	}
+	if port, ok := conf.Env["PORT"]; ok {
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || conf.Port > 0 && conf.Port != p {
+			return nil, fmt.Errorf("Invalid PORT %s, set the port with --port instead", port)
+		}
+	}
+
 	packs := []Pack{
-		&GoPack{workDir},
-		&PhpPack{workDir},
-		&PythonPack{workDir},
-		&RubyPack{workDir},
-		&NodePack{workDir},
+		&GoPack{WorkDir: workDir, Main: conf.GoMain, Tags: conf.GoTags},
+		&PhpPack{WorkDir: workDir, Variant: conf.Variant, Ini: conf.PhpIni, Rules: rules},
+		&PythonPack{WorkDir: workDir, Rules: rules},
+		&RubyPack{WorkDir: workDir, Rules: rules},
+		&NodePack{WorkDir: workDir, Rules: rules},
 	}
 
 	detected := []*Buildpack{}
@@ -84,7 +137,7 @@ func Detect(workDir string) (pack *Buildpack, err error) {
 			continue
 		}
 
-		pack = &Buildpack{}
+		pack = &Buildpack{Config: conf, pack: p}
 		pack.Metadata = p.Metadata()
 		pack.Metadata.Name = p.Name()
 		pack.Metadata.Command, err = p.Command()
@@ -106,6 +159,16 @@ func Detect(workDir string) (pack *Buildpack, err error) {
 	}
 
 	pack = detected[0]
+	if fw := detectFramework(pack.pack, workDir); fw != nil {
+		fw.apply(pack.pack, workDir, pack.Metadata)
+	}
+	if resolver, ok := pack.pack.(versionResolver); ok {
+		pack.Metadata.Version, err = resolver.resolveVersion(pack.Metadata.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	getPackages(pack.Metadata, conf.SystemPackages, packVersions(pack.pack))
 	err = getPath(workDir, pack.Metadata)
 	if err != nil {
 		return nil, err
@@ -115,14 +178,38 @@ func Detect(workDir string) (pack *Buildpack, err error) {
 	if err != nil {
 		return nil, err
 	}
+	getDistroPackages(pack.Metadata, rules.Debian)
+
+	err = getDepends(pack.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
 	err = getTools(workDir, pack.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	applyConfig(conf, pack.Metadata)
+	if health := pack.Metadata.Health; health != "" && !healthRegex.MatchString(health) {
+		return nil, fmt.Errorf("Invalid healthcheck path %s", conf.Healthcheck)
+	}
+	for _, env := range []map[string]string{pack.Metadata.Env, pack.Metadata.RunEnv} {
+		for key, value := range env {
+			if !envKeyRegex.MatchString(key) || strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("Invalid environment variable %s", key)
+			}
+		}
+	}
 	return
 }
 
 type Buildpack struct {
+	Config   *Config
+	Events   EventHandler
 	Metadata *Metadata
 	WorkDir  string
+	pack     Pack
 }
 
 func (b *Buildpack) BuildImage(name string) (string, error) {
@@ -141,16 +228,41 @@ func (b *Buildpack) BuildImage(name string) (string, error) {
 	}
 	defer imageClient.Close()
 
-	logger := &buildLogger{}
-	daemon := builder.DaemonBuilder{
-		Client:           imageClient,
-		ContextDirectory: b.WorkDir,
-		Dockerfile:       ".jet/Dockerfile",
-		LoggerFactory:    logger,
+	logger := &buildLogger{
+		client:  imageClient,
+		handler: b.Events,
+	}
+	buildArgs := map[string]*string{}
+	for key, value := range b.installArgs() {
+		value := value
+		buildArgs[key] = &value
+	}
+	if b.Config != nil {
+		for key, value := range b.Config.BuildArgs {
+			value := value
+			buildArgs[key] = &value
+		}
+	}
+
+	buildCtx, err := builder.CreateTar(b.WorkDir, ".jet/Dockerfile")
+	if err != nil {
+		return "", err
 	}
+	defer buildCtx.Close()
 
 	ctx := context.Background()
-	return logger.imageID, daemon.Build(ctx, name)
+	err = logger.build(ctx, buildCtx, types.ImageBuildOptions{
+		BuildArgs:  buildArgs,
+		Dockerfile: ".jet/Dockerfile",
+		Labels:     b.Labels(name),
+		Remove:     true,
+		Tags:       []string{name},
+	})
+	logger.flush()
+	if err != nil {
+		return "", newBuildError(err, logger.step, logger.failure, logger.output)
+	}
+	return logger.imageID, nil
 }
 
 func (b *Buildpack) GetDockerfile() (string, error) {
@@ -212,25 +324,9 @@ func (b *Buildpack) createDockerignore() error {
 	return w.Flush()
 }
 
-var (
-	dockerTemplate = template.Must(template.New("Dockerfile").Parse(dockerString))
-	imageRegex     = regexp.MustCompile(`^ ---> ([0-9a-f]+)\s*$`)
-)
-
-type buildLogger struct {
-	logger.RawLogger
-	imageID string
-}
-
-func (b *buildLogger) Out(message []byte) {
-	msg := string(message)
-	matches := imageRegex.FindStringSubmatch(msg)
-	if len(matches) > 1 {
-		b.imageID = matches[1]
-	}
-	fmt.Print(msg)
-}
-
-func (b *buildLogger) CreateBuildLogger(_ string) logger.Logger {
-	return b
-}
+var dockerTemplate = template.Must(template.New("Dockerfile").Funcs(template.FuncMap{
+	"join":     strings.Join,
+	"packages": buildPackages,
+	"quote":    envQuote,
+	"runtime":  runtimeStage,
+}).Parse(dockerString))
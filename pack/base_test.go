@@ -1,6 +1,7 @@
 package pack_test
 
 import (
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aquasecurity/go-version/pkg/version"
 	"github.com/lade-io/jet/pack"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
@@ -71,6 +73,277 @@ func TestDetect(t *testing.T) {
 	})
 }
 
+func TestDetectFramework(t *testing.T) {
+	tests := []struct {
+		dir       string
+		framework string
+		command   string
+		install   []string
+		release   string
+		env       map[string]string
+		processes map[string]string
+	}{
+		{dir: "go/echo", framework: "echo"},
+		{dir: "go/gin", framework: "gin", env: map[string]string{"GIN_MODE": "release"}},
+		{dir: "node/node12", framework: "express"},
+		{
+			dir:       "node/nestjs",
+			framework: "nestjs",
+			command:   "node dist/main",
+			install:   []string{"nest build"},
+		},
+		{
+			dir:       "node/nextjs",
+			framework: "nextjs",
+			command:   "next start -p ${PORT}",
+			install:   []string{"npm run build"},
+		},
+		{
+			dir:       "php/laravel",
+			framework: "laravel",
+			install: []string{
+				"composer dump-autoload --optimize --no-dev",
+				"mkdir -p storage/framework/cache storage/framework/sessions storage/framework/views storage/logs bootstrap/cache",
+				"chmod -R ug+rwX storage/framework/cache storage/framework/sessions storage/framework/views storage/logs bootstrap/cache",
+				"php artisan route:cache",
+				"php artisan view:cache",
+			},
+			processes: map[string]string{
+				"scheduler": "php artisan schedule:work",
+				"worker":    "php artisan queue:work",
+			},
+		},
+		{
+			dir:       "php/symfony",
+			framework: "symfony",
+			install: []string{
+				"composer dump-autoload --optimize --no-dev",
+				"mkdir -p var/cache var/log",
+				"chmod -R ug+rwX var/cache var/log",
+				"php bin/console cache:warmup",
+			},
+			env: map[string]string{"APP_ENV": "prod"},
+		},
+		{
+			dir:       "python/django",
+			framework: "django",
+			install:   []string{"python manage.py collectstatic --noinput"},
+		},
+		{dir: "python/fastapi", framework: "fastapi"},
+		{dir: "python/flask", framework: "flask"},
+		{
+			dir:       "ruby/rails4",
+			framework: "rails",
+			install:   []string{"SECRET_KEY_BASE=dummy bundle exec rake assets:precompile"},
+			release:   "bundle exec rake db:migrate",
+			env: map[string]string{
+				"BUNDLE_WITHOUT":           "development:test",
+				"RAILS_ENV":                "production",
+				"RAILS_SERVE_STATIC_FILES": "true",
+			},
+		},
+		{dir: "ruby/sinatra", framework: "sinatra", env: map[string]string{"RACK_ENV": "production"}},
+	}
+	for _, test := range tests {
+		bp, err := pack.Detect(filepath.Join(testDir, test.dir))
+		require.NoError(t, err, test.dir)
+
+		meta := bp.Metadata
+		assert.Equal(t, test.framework, meta.Framework, test.dir)
+		if test.command != "" {
+			assert.Equal(t, test.command, meta.Command, test.dir)
+		}
+		assert.Subset(t, meta.Install, test.install, test.dir)
+		assert.Equal(t, test.release, meta.Release, test.dir)
+		for key, value := range test.env {
+			assert.Equal(t, value, meta.Env[key], test.dir)
+		}
+		assert.Equal(t, len(test.processes), len(meta.Processes), test.dir)
+		for name, command := range test.processes {
+			assert.Equal(t, command, meta.Processes[name], test.dir)
+		}
+	}
+}
+
+func TestDetectPyProject(t *testing.T) {
+	tests := []struct {
+		dir     string
+		command string
+		python  string
+		tool    *pack.Tool
+	}{
+		{
+			dir:     "python/poetry",
+			command: "gunicorn app:app",
+			python:  "^3.11",
+			tool: &pack.Tool{
+				Name:    "poetry",
+				Install: []string{"lock", "export --without-hashes -o requirements.txt"},
+			},
+		},
+		{
+			dir:     "python/pyproject",
+			command: "gunicorn server:app",
+			python:  ">=3.10",
+			tool: &pack.Tool{
+				Name:    "pip",
+				Install: []string{"install 'Flask>=2.3' 'gunicorn>=21.2'"},
+			},
+		},
+	}
+	for _, test := range tests {
+		bp, err := pack.Detect(filepath.Join(testDir, test.dir))
+		require.NoError(t, err, test.dir)
+
+		meta := bp.Metadata
+		assert.Equal(t, "flask", meta.Framework, test.dir)
+		assert.Equal(t, test.command, meta.Command, test.dir)
+		constraints, err := version.NewConstraints(test.python)
+		require.NoError(t, err, test.dir)
+		v, err := version.Parse(meta.Version)
+		require.NoError(t, err, test.dir)
+		assert.True(t, constraints.Check(v), test.dir)
+		var install []string
+		for _, tool := range meta.Tools {
+			if tool.Name == test.tool.Name {
+				install = tool.Install
+			}
+		}
+		assert.Equal(t, test.tool.Install, install, test.dir)
+	}
+}
+
+func TestDetectWorkspace(t *testing.T) {
+	bp, err := pack.Detect(filepath.Join(testDir, "node", "pnpm"))
+	require.NoError(t, err)
+
+	tool := bp.Metadata.Tools[0]
+	assert.Equal(t, "pnpm", tool.Name)
+	assert.Contains(t, tool.Download, "corepack enable")
+	assert.Equal(t, []string{"install"}, tool.Install)
+	assert.Equal(t, map[string][]string{
+		".":                 {"package.json", "pnpm-workspace.yaml"},
+		"packages/greeting": {"packages/greeting/package.json"},
+	}, tool.Copy)
+}
+
+func TestDetectPhpIni(t *testing.T) {
+	conf := &pack.Config{PhpIni: map[string]string{"error_prepend_string": "it's"}}
+	bp, err := pack.DetectConfig(filepath.Join(testDir, "php", "preload"), conf)
+	require.NoError(t, err)
+
+	var args []string
+	for _, depend := range bp.Metadata.Depends {
+		if len(depend.Args) > 0 && depend.Args[0] == "docker-php-ext-enable opcache" {
+			args = depend.Args
+		}
+	}
+	for _, line := range []string{
+		`error_prepend_string = it'\''s`,
+		"expose_php = Off",
+		"opcache.jit_prof_threshold = 0.005",
+		"opcache.max_accelerated_files = 1000000",
+		"opcache.preload = /var/www/config/preload.php",
+		"opcache.preload_user = www-data",
+		"session.cookie_httponly = On",
+	} {
+		assert.Contains(t, args, "echo '"+line+"' >> $PHP_INI_DIR/conf.d/zz-jet.ini")
+	}
+}
+
+func TestSBOM(t *testing.T) {
+	bp, err := pack.Detect(filepath.Join(testDir, "node", "node12"))
+	require.NoError(t, err)
+
+	purls := map[string]bool{}
+	components := bp.Components()
+	for _, c := range components {
+		purls[c.Purl] = true
+	}
+	assert.Equal(t, "pkg:docker/library/node@"+bp.Metadata.Version, components[0].Purl)
+	assert.True(t, purls["pkg:npm/express@4.0.0"])
+	assert.True(t, purls["pkg:npm/logfmt@1.1.3"])
+
+	b, err := bp.SBOM("node12", pack.SBOMCycloneDX)
+	require.NoError(t, err)
+	cdx := struct {
+		BomFormat    string `json:"bomFormat"`
+		SpecVersion  string `json:"specVersion"`
+		SerialNumber string `json:"serialNumber"`
+		Version      int    `json:"version"`
+		Metadata     struct {
+			Timestamp string            `json:"timestamp"`
+			Component map[string]string `json:"component"`
+		} `json:"metadata"`
+		Components []map[string]string `json:"components"`
+	}{}
+	require.NoError(t, json.Unmarshal(b, &cdx))
+	assert.Equal(t, "CycloneDX", cdx.BomFormat)
+	assert.Equal(t, "1.5", cdx.SpecVersion)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, cdx.SerialNumber)
+	assert.Equal(t, 1, cdx.Version)
+	assert.NotEmpty(t, cdx.Metadata.Timestamp)
+	assert.Equal(t, map[string]string{"bom-ref": "image", "type": "container", "name": "node12"}, cdx.Metadata.Component)
+	require.Len(t, cdx.Components, len(components))
+	for i, c := range cdx.Components {
+		assert.NotEmpty(t, c["bom-ref"])
+		assert.NotEmpty(t, c["type"])
+		assert.NotEmpty(t, c["name"])
+		assert.Equal(t, components[i].Purl, c["purl"])
+	}
+
+	b, err = bp.SBOM("node12", pack.SBOMSPDX)
+	require.NoError(t, err)
+	spdx := struct {
+		SPDXVersion       string `json:"spdxVersion"`
+		DataLicense       string `json:"dataLicense"`
+		SPDXID            string `json:"SPDXID"`
+		Name              string `json:"name"`
+		DocumentNamespace string `json:"documentNamespace"`
+		CreationInfo      struct {
+			Created  string   `json:"created"`
+			Creators []string `json:"creators"`
+		} `json:"creationInfo"`
+		Packages []struct {
+			SPDXID           string `json:"SPDXID"`
+			Name             string `json:"name"`
+			DownloadLocation string `json:"downloadLocation"`
+			ExternalRefs     []struct {
+				Category string `json:"referenceCategory"`
+				Type     string `json:"referenceType"`
+				Locator  string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		Relationships []map[string]string `json:"relationships"`
+	}{}
+	require.NoError(t, json.Unmarshal(b, &spdx))
+	assert.Equal(t, "SPDX-2.3", spdx.SPDXVersion)
+	assert.Equal(t, "CC0-1.0", spdx.DataLicense)
+	assert.Equal(t, "SPDXRef-DOCUMENT", spdx.SPDXID)
+	assert.Equal(t, "node12", spdx.Name)
+	assert.Regexp(t, `^https://lade\.io/spdx/node12-[0-9a-f-]{36}$`, spdx.DocumentNamespace)
+	assert.NotEmpty(t, spdx.CreationInfo.Created)
+	assert.Equal(t, []string{"Tool: jet"}, spdx.CreationInfo.Creators)
+	require.Len(t, spdx.Packages, len(components)+1)
+	for i, pkg := range spdx.Packages[1:] {
+		assert.NotEmpty(t, pkg.SPDXID)
+		assert.Equal(t, components[i].Name, pkg.Name)
+		assert.NotEmpty(t, pkg.DownloadLocation)
+		require.Len(t, pkg.ExternalRefs, 1)
+		assert.Equal(t, "purl", pkg.ExternalRefs[0].Type)
+		assert.Equal(t, components[i].Purl, pkg.ExternalRefs[0].Locator)
+	}
+
+	require.Len(t, spdx.Relationships, len(components)+1)
+	assert.Equal(t, map[string]string{
+		"spdxElementId":      "SPDXRef-DOCUMENT",
+		"relationshipType":   "DESCRIBES",
+		"relatedSpdxElement": "SPDXRef-Image",
+	}, spdx.Relationships[0])
+	assert.Equal(t, "DESCENDANT_OF", spdx.Relationships[1]["relationshipType"])
+	assert.Equal(t, "CONTAINS", spdx.Relationships[2]["relationshipType"])
+}
+
 func TestBuild(t *testing.T) {
 	forEachCase(t, func(t *testing.T, testPack, testCase, workDir string) {
 		bp, err := pack.Detect(workDir)
@@ -82,7 +355,7 @@ func TestBuild(t *testing.T) {
 		resource, err := testPool.RunWithOptions(&dockertest.RunOptions{
 			Repository:   testCase,
 			ExposedPorts: []string{"3000"},
-			Env:          []string{"PORT=3000"},
+			Env:          []string{"PORT=3000", "SECRET_KEY_BASE=" + testCase},
 		})
 		require.NoError(t, err)
 
@@ -111,6 +384,39 @@ func TestBuild(t *testing.T) {
 	})
 }
 
+func TestBuildPhpFpm(t *testing.T) {
+	workDir := filepath.Join(testDir, "php", "symfony")
+	bp, err := pack.DetectConfig(workDir, &pack.Config{Variant: "fpm"})
+	require.NoError(t, err)
+	imageID, err := bp.BuildImage("symfony-fpm")
+	defer assertRemoveImage(t, imageID)
+	require.NoError(t, err)
+
+	resource, err := testPool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "symfony-fpm",
+		ExposedPorts: []string{"3000"},
+		Env:          []string{"PORT=3000"},
+	})
+	require.NoError(t, err)
+
+	var body []byte
+	err = testPool.Retry(func() error {
+		resp, err := http.Get("http://localhost:" + resource.GetPort("3000/tcp"))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return errors.New(resp.Status)
+		}
+		body, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `<body id="homepage">`)
+	assert.NoError(t, testPool.Purge(resource))
+}
+
 func assertRemoveImage(t *testing.T, imageID string) {
 	history, err := testPool.Client.ImageHistory(imageID)
 	require.NoError(t, err)
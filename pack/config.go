@@ -0,0 +1,131 @@
+package pack
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultPort = 3000
+
+var configFiles = []string{"jet.yml", "jet.yaml"}
+
+type Config struct {
+	BuildArgs      map[string]string          `yaml:"build_args"`
+	Env            map[string]string          `yaml:"env"`
+	GoMain         string                     `yaml:"go_main"`
+	GoTags         []string                   `yaml:"go_tags"`
+	Healthcheck    string                     `yaml:"healthcheck"`
+	Labels         map[string]string          `yaml:"labels"`
+	PhpIni         map[string]string          `yaml:"php_ini"`
+	Port           int                        `yaml:"port"`
+	Processes      map[string]string          `yaml:"processes"`
+	Release        string                     `yaml:"release"`
+	Rules          string                     `yaml:"rules"`
+	SingleStage    bool                       `yaml:"single_stage"`
+	SystemPackages map[string]*SystemPackages `yaml:"system_packages"`
+	Variant        string                     `yaml:"variant"`
+}
+
+type SystemPackages struct {
+	Build   []string `yaml:"build"`
+	Run     []string `yaml:"run"`
+	Version string   `yaml:"version"`
+}
+
+func LoadConfig(workDir, file string) (*Config, error) {
+	conf := &Config{}
+	if file == "" {
+		for _, name := range configFiles {
+			if fileExists(workDir, name) {
+				file = filepath.Join(workDir, name)
+				break
+			}
+		}
+	}
+	if file == "" {
+		return conf, nil
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(b, conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (c *Config) SetBuildArg(key, value string) {
+	if c.BuildArgs == nil {
+		c.BuildArgs = map[string]string{}
+	}
+	c.BuildArgs[key] = value
+}
+
+func (c *Config) SetEnv(key, value string) {
+	if c.Env == nil {
+		c.Env = map[string]string{}
+	}
+	c.Env[key] = value
+}
+
+func (c *Config) SetLabel(key, value string) {
+	if c.Labels == nil {
+		c.Labels = map[string]string{}
+	}
+	c.Labels[key] = value
+}
+
+func applyConfig(conf *Config, meta *Metadata) {
+	for key := range conf.BuildArgs {
+		meta.Args = append(meta.Args, key)
+	}
+	sort.Strings(meta.Args)
+
+	if conf.Port > 0 {
+		meta.Port = conf.Port
+	} else if port, err := strconv.Atoi(conf.Env["PORT"]); err == nil {
+		meta.Port = port
+	} else if meta.Port == 0 {
+		meta.Port = defaultPort
+	}
+
+	if conf.SingleStage {
+		meta.Prune = nil
+		meta.RunEnv = nil
+	}
+	if len(meta.Prune) == 0 && len(meta.Keep) == 0 || conf.SingleStage {
+		meta.Packages = append(meta.Packages, meta.BuildPackages...)
+		meta.BuildPackages = nil
+	}
+
+	if conf.Release != "" {
+		meta.Release = conf.Release
+	}
+	for name, command := range conf.Processes {
+		if meta.Processes == nil {
+			meta.Processes = map[string]string{}
+		}
+		meta.Processes[name] = command
+	}
+
+	if conf.Healthcheck != "" {
+		meta.Health = "/" + strings.TrimPrefix(conf.Healthcheck, "/")
+	}
+
+	if meta.Env == nil {
+		meta.Env = map[string]string{}
+	}
+	meta.Env["PORT"] = strconv.Itoa(meta.Port)
+	for key, value := range conf.Env {
+		meta.Env[key] = value
+		delete(meta.RunEnv, key)
+	}
+}
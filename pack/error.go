@@ -0,0 +1,67 @@
+package pack
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const outputLines = 20
+
+var (
+	codeRegex    = regexp.MustCompile(`Code: (\d+)$`)
+	commandRegex = regexp.MustCompile(`(?:The command '(.*)' returned a non-zero code|executor failed running \[(.*)\]: exit code): (\d+)`)
+)
+
+type BuildError struct {
+	Step        int
+	Steps       int
+	Instruction string
+	Command     string
+	ExitCode    int
+	Output      []string
+	Message     string
+}
+
+func (e *BuildError) Error() string {
+	if e.Step == 0 {
+		return e.Message
+	}
+	if e.ExitCode == 0 {
+		return fmt.Sprintf("Step %d/%d failed: %s: %s", e.Step, e.Steps, e.Instruction, e.Message)
+	}
+	return fmt.Sprintf("Step %d/%d failed with exit code %d: %s", e.Step, e.Steps, e.ExitCode, e.Instruction)
+}
+
+func newBuildError(err error, step *Event, failure string, output []string) error {
+	if step == nil && failure == "" {
+		return err
+	}
+
+	buildErr := &BuildError{
+		Message: failure,
+		Output:  output,
+	}
+	if buildErr.Message == "" {
+		buildErr.Message = err.Error()
+	}
+
+	if step != nil {
+		buildErr.Step = step.Step
+		buildErr.Steps = step.Steps
+		buildErr.Instruction = step.Instruction
+		if strings.HasPrefix(step.Instruction, "RUN ") {
+			buildErr.Command = strings.TrimPrefix(step.Instruction, "RUN ")
+		}
+	}
+
+	if matches := commandRegex.FindStringSubmatch(buildErr.Message); len(matches) > 3 {
+		command := matches[1] + matches[2]
+		buildErr.Command = strings.TrimPrefix(command, "/bin/sh -c ")
+		buildErr.ExitCode, _ = strconv.Atoi(matches[3])
+	} else if matches := codeRegex.FindStringSubmatch(err.Error()); len(matches) > 1 {
+		buildErr.ExitCode, _ = strconv.Atoi(matches[1])
+	}
+	return buildErr
+}
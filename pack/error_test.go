@@ -0,0 +1,77 @@
+package pack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBuildError(t *testing.T) {
+	step := &Event{Step: 3, Steps: 5, Instruction: "RUN npm ci"}
+	tests := []struct {
+		name     string
+		err      error
+		step     *Event
+		failure  string
+		command  string
+		code     int
+		expected string
+	}{
+		{
+			name:     "classic",
+			err:      errors.New("build failed"),
+			step:     step,
+			failure:  "The command '/bin/sh -c npm ci' returned a non-zero code: 127",
+			command:  "npm ci",
+			code:     127,
+			expected: "Step 3/5 failed with exit code 127: RUN npm ci",
+		},
+		{
+			name:     "buildkit",
+			err:      errors.New("build failed"),
+			step:     step,
+			failure:  "executor failed running [/bin/sh -c npm ci --omit=dev]: exit code: 2",
+			command:  "npm ci --omit=dev",
+			code:     2,
+			expected: "Step 3/5 failed with exit code 2: RUN npm ci",
+		},
+		{
+			name:     "code",
+			err:      errors.New("Status: build failed, Code: 3"),
+			step:     step,
+			command:  "npm ci",
+			code:     3,
+			expected: "Step 3/5 failed with exit code 3: RUN npm ci",
+		},
+		{
+			name:     "unmatched",
+			err:      errors.New("build failed"),
+			step:     step,
+			failure:  "failed to register layer: no space left on device",
+			command:  "npm ci",
+			expected: "Step 3/5 failed: RUN npm ci: failed to register layer: no space left on device",
+		},
+		{
+			name:     "no step",
+			err:      errors.New("build failed"),
+			failure:  "pull access denied for node",
+			expected: "pull access denied for node",
+		},
+		{
+			name:     "passthrough",
+			err:      errors.New("Cannot connect to the Docker daemon"),
+			expected: "Cannot connect to the Docker daemon",
+		},
+	}
+	for _, test := range tests {
+		err := newBuildError(test.err, test.step, test.failure, nil)
+		assert.EqualError(t, err, test.expected, test.name)
+
+		var buildErr *BuildError
+		if errors.As(err, &buildErr) {
+			assert.Equal(t, test.command, buildErr.Command, test.name)
+			assert.Equal(t, test.code, buildErr.ExitCode, test.name)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+type EventType string
+
+const (
+	EventStepStart  EventType = "step_start"
+	EventStepFinish EventType = "step_finish"
+	EventCacheHit   EventType = "cache_hit"
+	EventOutput     EventType = "output"
+	EventError      EventType = "error"
+)
+
+type Event struct {
+	Type        EventType     `json:"type"`
+	Time        time.Time     `json:"time"`
+	Step        int           `json:"step,omitempty"`
+	Steps       int           `json:"steps,omitempty"`
+	Instruction string        `json:"instruction,omitempty"`
+	Message     string        `json:"message,omitempty"`
+	ImageID     string        `json:"image_id,omitempty"`
+	Cached      bool          `json:"cached,omitempty"`
+	Size        int64         `json:"size,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+type EventHandler func(event *Event)
+
+var (
+	cacheRegex = regexp.MustCompile(`^ ---> Using cache\s*$`)
+	fromRegex  = regexp.MustCompile(`(?i)^FROM\s`)
+	imageRegex = regexp.MustCompile(`^ ---> ([0-9a-f]+)\s*$`)
+	stepRegex  = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+	skipRegex  = regexp.MustCompile(`^( ---> Running in |Removing intermediate container |Successfully (built|tagged) )`)
+)
+
+type buildLogger struct {
+	client  client.ImageAPIClient
+	handler EventHandler
+	imageID string
+	buffer  []byte
+	failure string
+	output  []string
+	size    int64
+	step    *Event
+}
+
+func (b *buildLogger) build(ctx context.Context, buildCtx io.Reader, options types.ImageBuildOptions) error {
+	response, err := b.client.ImageBuild(ctx, buildCtx, options)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	err = jsonmessage.DisplayJSONMessagesStream(response.Body, b, 0, false, nil)
+	var jerr *jsonmessage.JSONError
+	if errors.As(err, &jerr) {
+		if jerr.Code == 0 {
+			jerr.Code = 1
+		}
+		b.Err([]byte(jerr.Error()))
+		return fmt.Errorf("Status: %s, Code: %d", jerr.Message, jerr.Code)
+	}
+	return err
+}
+
+func (b *buildLogger) Out(message []byte) {
+	if b.handler == nil {
+		fmt.Print(string(message))
+	}
+
+	b.buffer = append(b.buffer, message...)
+	for {
+		i := bytes.IndexByte(b.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(b.buffer[:i]), "\r")
+		b.buffer = b.buffer[i+1:]
+		b.parse(line)
+	}
+}
+
+func (b *buildLogger) Err(message []byte) {
+	if b.handler == nil {
+		fmt.Fprint(os.Stderr, string(message))
+	}
+
+	event := b.event(EventError)
+	event.Error = strings.TrimSpace(string(message))
+	b.failure = event.Error
+	b.emit(event)
+}
+
+func (b *buildLogger) Write(message []byte) (int, error) {
+	b.Out(message)
+	return len(message), nil
+}
+
+func (b *buildLogger) flush() {
+	if len(b.buffer) > 0 {
+		line := strings.TrimRight(string(b.buffer), "\r\n")
+		b.buffer = nil
+		b.parse(line)
+	}
+}
+
+func (b *buildLogger) parse(line string) {
+	if matches := stepRegex.FindStringSubmatch(line); len(matches) > 3 {
+		event := &Event{
+			Type:        EventStepStart,
+			Time:        time.Now(),
+			Instruction: matches[3],
+		}
+		event.Step, _ = strconv.Atoi(matches[1])
+		event.Steps, _ = strconv.Atoi(matches[2])
+		b.output = nil
+		b.step = event
+		b.emit(event)
+		return
+	}
+
+	if cacheRegex.MatchString(line) {
+		if b.step != nil {
+			b.step.Cached = true
+		}
+		b.emit(b.event(EventCacheHit))
+		return
+	}
+
+	if matches := imageRegex.FindStringSubmatch(line); len(matches) > 1 {
+		b.imageID = matches[1]
+		b.finish()
+		return
+	}
+
+	if strings.TrimSpace(line) == "" || skipRegex.MatchString(line) {
+		return
+	}
+
+	b.output = append(b.output, line)
+	if len(b.output) > outputLines {
+		b.output = b.output[len(b.output)-outputLines:]
+	}
+
+	event := b.event(EventOutput)
+	event.Message = line
+	b.emit(event)
+}
+
+func (b *buildLogger) finish() {
+	event := b.event(EventStepFinish)
+	event.ImageID = b.imageID
+	if b.step != nil {
+		event.Cached = b.step.Cached
+		event.Duration = event.Time.Sub(b.step.Time)
+	}
+
+	if b.client != nil && b.handler != nil {
+		ctx := context.Background()
+		image, _, err := b.client.ImageInspectWithRaw(ctx, b.imageID)
+		if err == nil && b.step != nil && fromRegex.MatchString(b.step.Instruction) {
+			b.size = image.Size
+		} else if err == nil {
+			if image.Size > b.size {
+				event.Size = image.Size - b.size
+			}
+			b.size = image.Size
+		}
+	}
+	b.emit(event)
+}
+
+func (b *buildLogger) event(eventType EventType) *Event {
+	event := &Event{
+		Type: eventType,
+		Time: time.Now(),
+	}
+	if b.step != nil {
+		event.Step = b.step.Step
+		event.Steps = b.step.Steps
+		event.Instruction = b.step.Instruction
+	}
+	return event
+}
+
+func (b *buildLogger) emit(event *Event) {
+	if b.handler != nil {
+		b.handler(event)
+	}
+}
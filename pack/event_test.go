@@ -0,0 +1,134 @@
+package pack
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildLoggerParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		err      string
+		expected []Event
+	}{
+		{
+			name:  "step",
+			lines: []string{"Step 2/5 : RUN npm ci"},
+			expected: []Event{
+				{Type: EventStepStart, Step: 2, Steps: 5, Instruction: "RUN npm ci"},
+			},
+		},
+		{
+			name:  "output",
+			lines: []string{"Step 2/5 : RUN npm ci", " ---> Running in 4f2a1c9e", "", "added 12 packages"},
+			expected: []Event{
+				{Type: EventStepStart, Step: 2, Steps: 5, Instruction: "RUN npm ci"},
+				{Type: EventOutput, Step: 2, Steps: 5, Instruction: "RUN npm ci", Message: "added 12 packages"},
+			},
+		},
+		{
+			name:  "image",
+			lines: []string{"Step 1/5 : FROM node:20", " ---> Using cache", " ---> 1a2b3c4d5e6f"},
+			expected: []Event{
+				{Type: EventStepStart, Step: 1, Steps: 5, Instruction: "FROM node:20"},
+				{Type: EventCacheHit, Step: 1, Steps: 5, Instruction: "FROM node:20"},
+				{Type: EventStepFinish, Step: 1, Steps: 5, Instruction: "FROM node:20", ImageID: "1a2b3c4d5e6f", Cached: true},
+			},
+		},
+		{
+			name:  "error",
+			lines: []string{"Step 3/5 : RUN make"},
+			err:   "The command '/bin/sh -c make' returned a non-zero code: 2\n",
+			expected: []Event{
+				{Type: EventStepStart, Step: 3, Steps: 5, Instruction: "RUN make"},
+				{Type: EventError, Step: 3, Steps: 5, Instruction: "RUN make", Error: "The command '/bin/sh -c make' returned a non-zero code: 2"},
+			},
+		},
+	}
+	for _, test := range tests {
+		var events []Event
+		b := &buildLogger{handler: func(event *Event) {
+			e := *event
+			e.Time, e.Duration = time.Time{}, 0
+			events = append(events, e)
+		}}
+		for _, line := range test.lines {
+			b.Out([]byte(line + "\n"))
+		}
+		if test.err != "" {
+			b.Err([]byte(test.err))
+			assert.Equal(t, test.expected[len(test.expected)-1].Error, b.failure, test.name)
+		}
+
+		assert.Equal(t, test.expected, events, test.name)
+	}
+}
+
+type stubImages struct {
+	client.ImageAPIClient
+	sizes  map[string]int64
+	stream string
+}
+
+func (s stubImages) ImageBuild(_ context.Context, _ io.Reader, _ types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+	return types.ImageBuildResponse{Body: ioutil.NopCloser(strings.NewReader(s.stream))}, nil
+}
+
+func (s stubImages) ImageInspectWithRaw(_ context.Context, image string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{ID: image, Size: s.sizes[image]}, nil, nil
+}
+
+func TestBuildLoggerSize(t *testing.T) {
+	var sizes []int64
+	b := &buildLogger{
+		client: stubImages{sizes: map[string]int64{
+			"aaaa": 1000, "bbbb": 1500, "cccc": 1400,
+			"dddd": 800, "eeee": 900,
+		}},
+		handler: func(event *Event) {
+			if event.Type == EventStepFinish {
+				sizes = append(sizes, event.Size)
+			}
+		},
+	}
+	for _, line := range []string{
+		"Step 1/5 : FROM python:3.12 AS build", " ---> aaaa",
+		"Step 2/5 : RUN pip install -r requirements.txt", " ---> bbbb",
+		"Step 3/5 : RUN rm -rf /tmp/cache", " ---> cccc",
+		"Step 4/5 : FROM python:3.12-slim", " ---> dddd",
+		"Step 5/5 : COPY --from=build /app ./", " ---> eeee",
+	} {
+		b.Out([]byte(line + "\n"))
+	}
+	assert.Equal(t, []int64{0, 500, 0, 0, 100}, sizes)
+}
+
+func TestBuildLoggerBuild(t *testing.T) {
+	var events []EventType
+	b := &buildLogger{
+		client: stubImages{stream: `{"stream":"Step 1/2 : FROM node:20\n"}
+{"stream":" ---> 1a2b3c4d5e6f\n"}
+{"stream":"Step 2/2 : RUN make\n"}
+{"errorDetail":{"code":2,"message":"The command '/bin/sh -c make' returned a non-zero code: 2"},` +
+			`"error":"The command '/bin/sh -c make' returned a non-zero code: 2"}
+`},
+		handler: func(event *Event) {
+			events = append(events, event.Type)
+		},
+	}
+	err := b.build(context.Background(), strings.NewReader(""), types.ImageBuildOptions{})
+	assert.EqualError(t, err, "Status: The command '/bin/sh -c make' returned a non-zero code: 2, Code: 2")
+	assert.Equal(t, []EventType{EventStepStart, EventStepFinish, EventStepStart, EventError}, events)
+
+	err = newBuildError(err, b.step, b.failure, b.output)
+	assert.EqualError(t, err, "Step 2/2 failed with exit code 2: RUN make")
+}
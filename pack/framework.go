@@ -0,0 +1,244 @@
+package pack
+
+import (
+	"strings"
+)
+
+type Framework struct {
+	Name      string
+	Language  string
+	Depends   []string
+	Files     []string
+	Command   string
+	Env       map[string]string
+	Processes map[string]string
+	Setup     func(p Pack, workDir string, meta *Metadata)
+}
+
+var frameworks = []*Framework{
+	{
+		Name:     "django",
+		Language: "python",
+		Depends:  []string{"django"},
+		Setup: func(p Pack, workDir string, meta *Metadata) {
+			if fileContains(workDir, "**/settings*.py", "STATIC_ROOT") {
+				meta.Install = append(meta.Install, "python manage.py collectstatic --noinput")
+			}
+		},
+	},
+	{
+		Name:     "fastapi",
+		Language: "python",
+		Depends:  []string{"fastapi"},
+	},
+	{
+		Name:     "flask",
+		Language: "python",
+		Depends:  []string{"flask"},
+	},
+	{
+		Name:     "rails",
+		Language: "ruby",
+		Depends:  []string{"rails", "railties"},
+		Files:    []string{"config/application.rb"},
+		Env: map[string]string{
+			"BUNDLE_WITHOUT":           "development:test",
+			"RAILS_ENV":                "production",
+			"RAILS_SERVE_STATIC_FILES": "true",
+		},
+		Setup: func(p Pack, workDir string, meta *Metadata) {
+			specs := p.(*RubyPack).specs()
+			for _, name := range []string{"sprockets", "propshaft", "webpacker"} {
+				if _, ok := specs[name]; ok {
+					meta.Install = append(meta.Install, "SECRET_KEY_BASE=dummy bundle exec rake assets:precompile")
+					break
+				}
+			}
+			if _, ok := specs["bootsnap"]; ok {
+				meta.Install = append(meta.Install, "bundle exec bootsnap precompile --gemfile app/ lib/")
+			}
+			if fileExists(workDir, "config/database.yml") {
+				meta.Release = "bundle exec rake db:migrate"
+			}
+		},
+	},
+	{
+		Name:     "sinatra",
+		Language: "ruby",
+		Depends:  []string{"sinatra"},
+		Env:      map[string]string{"RACK_ENV": "production"},
+	},
+	{
+		Name:     "laravel",
+		Language: "php",
+		Depends:  []string{"laravel/framework"},
+		Files:    []string{"artisan"},
+		Processes: map[string]string{
+			"scheduler": "php artisan schedule:work",
+			"worker":    "php artisan queue:work",
+		},
+		Setup: func(p Pack, workDir string, meta *Metadata) {
+			php := p.(*PhpPack)
+			php.frameworkSetup(meta, "storage/framework/cache", "storage/framework/sessions",
+				"storage/framework/views", "storage/logs", "bootstrap/cache")
+			// config:cache is skipped, it would bake the build environment into the image.
+			meta.Install = append(meta.Install, "php artisan route:cache", "php artisan view:cache")
+		},
+	},
+	{
+		Name:     "symfony",
+		Language: "php",
+		Depends:  []string{"symfony/framework-bundle"},
+		Files:    []string{"bin/console"},
+		Env:      map[string]string{"APP_ENV": "prod"},
+		Setup: func(p Pack, workDir string, meta *Metadata) {
+			php := p.(*PhpPack)
+			php.frameworkSetup(meta, "var/cache", "var/log")
+			meta.Install = append(meta.Install, "php bin/console cache:warmup")
+
+			requires := php.requires()
+			if _, ok := requires["symfony/messenger"]; ok {
+				meta.Processes["worker"] = "php bin/console messenger:consume async"
+			}
+			if _, ok := requires["symfony/scheduler"]; ok {
+				meta.Processes["scheduler"] = "php bin/console messenger:consume scheduler_default"
+			}
+		},
+	},
+	{
+		Name:     "nextjs",
+		Language: "node",
+		Depends:  []string{"next"},
+		Command:  "next start -p ${PORT}",
+		Setup: func(p Pack, workDir string, meta *Metadata) {
+			if !p.(*NodePack).scripts()["build"] {
+				meta.Install = append(meta.Install, "next build")
+			}
+		},
+	},
+	{
+		Name:     "nestjs",
+		Language: "node",
+		Depends:  []string{"@nestjs/core"},
+		Command:  "node dist/main",
+		Setup: func(p Pack, workDir string, meta *Metadata) {
+			if !p.(*NodePack).scripts()["build"] {
+				meta.Install = append(meta.Install, "nest build")
+			}
+		},
+	},
+	{
+		Name:     "express",
+		Language: "node",
+		Depends:  []string{"express"},
+	},
+	{
+		Name:     "gin",
+		Language: "golang",
+		Depends:  []string{"github.com/gin-gonic/gin"},
+		Env:      map[string]string{"GIN_MODE": "release"},
+	},
+	{
+		Name:     "echo",
+		Language: "golang",
+		Depends:  []string{"github.com/labstack/echo"},
+	},
+}
+
+func detectFramework(p Pack, workDir string) *Framework {
+	language, depends := packDepends(p)
+	for _, fw := range frameworks {
+		if fw.Language != language || !fw.matches(workDir, depends) {
+			continue
+		}
+		return fw
+	}
+	return nil
+}
+
+func packDepends(p Pack) (string, map[string]bool) {
+	depends := map[string]bool{}
+	switch p := p.(type) {
+	case *GoPack:
+		for name := range p.requires() {
+			depends[name] = true
+		}
+		return "golang", depends
+	case *NodePack:
+		return "node", p.packages()
+	case *PhpPack:
+		for name := range p.requires() {
+			depends[name] = true
+		}
+		return "php", depends
+	case *PythonPack:
+		return "python", p.requirements()
+	case *RubyPack:
+		for name := range p.specs() {
+			depends[name] = true
+		}
+		return "ruby", depends
+	}
+	return "", depends
+}
+
+func packVersions(p Pack) map[string]string {
+	versions := map[string]string{}
+	_, depends := packDepends(p)
+	for name := range depends {
+		versions[name] = ""
+	}
+
+	if lister, ok := p.(dependencyLister); ok {
+		for _, dep := range lister.Dependencies() {
+			if _, ok := versions[dep.Name]; ok {
+				versions[dep.Name] = dep.Version
+			}
+		}
+	}
+	return versions
+}
+
+func (f *Framework) apply(p Pack, workDir string, meta *Metadata) {
+	meta.Framework = f.Name
+	if f.Command != "" {
+		meta.Command = f.Command
+	}
+
+	if len(f.Env) > 0 && meta.Env == nil {
+		meta.Env = map[string]string{}
+	}
+	for key, value := range f.Env {
+		if _, ok := meta.Env[key]; !ok {
+			meta.Env[key] = value
+		}
+	}
+
+	if meta.Processes == nil {
+		meta.Processes = map[string]string{}
+	}
+	for name, command := range f.Processes {
+		meta.Processes[name] = command
+	}
+
+	if f.Setup != nil {
+		f.Setup(p, workDir, meta)
+	}
+}
+
+func (f *Framework) matches(workDir string, depends map[string]bool) bool {
+	for _, file := range f.Files {
+		if !fileExists(workDir, file) {
+			return false
+		}
+	}
+
+	for _, name := range f.Depends {
+		for dep := range depends {
+			if dep == name || strings.HasPrefix(dep, name+"/v") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package pack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFrameworkFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		pack     func(dir string) Pack
+		files    map[string]string
+		expected string
+	}{
+		{
+			name:  "python without framework",
+			pack:  func(dir string) Pack { return &PythonPack{WorkDir: dir} },
+			files: map[string]string{"requirements.txt": "requests==2.31.0\n"},
+		},
+		{
+			name:     "rails without application",
+			pack:     func(dir string) Pack { return &RubyPack{WorkDir: dir} },
+			files:    map[string]string{"Gemfile.lock": "GEM\n  specs:\n    rails (7.1.2)\n    sinatra (3.1.0)\n"},
+			expected: "sinatra",
+		},
+		{
+			name:  "laravel without artisan",
+			pack:  func(dir string) Pack { return &PhpPack{WorkDir: dir} },
+			files: map[string]string{"composer.json": `{"require": {"laravel/framework": "^11.0"}}`},
+		},
+	}
+	for _, test := range tests {
+		dir := writeFiles(t, test.files)
+		fw := detectFramework(test.pack(dir), dir)
+		if test.expected == "" {
+			assert.Nil(t, fw, test.name)
+			continue
+		}
+		require.NotNil(t, fw, test.name)
+		assert.Equal(t, test.expected, fw.Name, test.name)
+	}
+}
+
+func TestApplyFrameworkBuild(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"package.json": `{"dependencies": {"next": "14.0.3"}}`})
+	p := &NodePack{WorkDir: dir}
+	fw := detectFramework(p, dir)
+	require.NotNil(t, fw)
+
+	meta := &Metadata{}
+	fw.apply(p, dir, meta)
+	assert.Equal(t, "nextjs", meta.Framework)
+	assert.Equal(t, "next start -p ${PORT}", meta.Command)
+	assert.Equal(t, []string{"next build"}, meta.Install)
+}
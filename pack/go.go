@@ -1,11 +1,23 @@
 package pack
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/go-version/pkg/version"
 	"github.com/cloudingcity/gomod"
 )
 
 type GoPack struct {
 	WorkDir string
+	Main    string
+	Tags    []string
 }
 
 func (g *GoPack) Detect() bool {
@@ -18,8 +30,9 @@ func (g *GoPack) Detect() bool {
 
 func (g *GoPack) Metadata() *Metadata {
 	meta := &Metadata{
-		Install: []string{"go install -v -ldflags '-s -w' ."},
-		User:    "web",
+		Install:     g.install(),
+		InstallArgs: []string{"GIT_COMMIT", "GIT_VERSION"},
+		User:        "web",
 	}
 	switch {
 	case fileExists(g.WorkDir, "Gopkg.toml"):
@@ -81,15 +94,159 @@ func (g *GoPack) Metadata() *Metadata {
 	return meta
 }
 
+func (g *GoPack) install() []string {
+	var install, tags []string
+	if len(g.Tags) > 0 {
+		tags = append(tags, "-tags "+strings.Join(g.Tags, ","))
+	}
+	mains := g.mains()
+
+	goVersion, _ := g.Version()
+	v, err := version.Parse(goVersion)
+	deps, _ := version.NewConstraints(">= 1.11")
+	if goVersion == "" || err == nil && deps.Check(v) {
+		list := append(append([]string{"go list -deps"}, tags...), cgoFormat)
+		install = append(install, `export CGO_ENABLED="${CGO_ENABLED:-$(`+
+			strings.Join(append(list, mains...), " ")+` | grep -q 1 && echo 1 || echo 0)}"`)
+	}
+
+	args := []string{"go install -v"}
+	trimpath, _ := version.NewConstraints(">= 1.13")
+	if goVersion == "" || err == nil && trimpath.Check(v) {
+		args = append(args, "-trimpath")
+	}
+	args = append(args, tags...)
+
+	args = append(args, `-ldflags "-s -w${GIT_VERSION:+ -X main.version=$GIT_VERSION}${GIT_COMMIT:+ -X main.commit=$GIT_COMMIT}"`)
+	return append(install, strings.Join(append(args, mains...), " "))
+}
+
+func (g *GoPack) mains() []string {
+	if g.Main != "" {
+		if main := filepath.Clean(g.Main); main != "." {
+			return []string{"./" + main}
+		}
+		return []string{"."}
+	}
+	if g.isMain(".") {
+		return []string{"."}
+	}
+
+	paths, _ := fileGlob(g.WorkDir, "cmd/*/*.go")
+	mains := []string{}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if !seen[dir] && g.isMain(dir) {
+			mains = append(mains, "./"+dir)
+		}
+		seen[dir] = true
+	}
+	if len(mains) == 0 {
+		return []string{"."}
+	}
+	sort.Strings(mains)
+	return mains
+}
+
+func (g *GoPack) isMain(dir string) bool {
+	paths, _ := fileGlob(g.WorkDir, filepath.Join(dir, "*.go"))
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		if b, err := fileRead(g.WorkDir, path); err == nil && goMainRegex.Match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GoPack) Dependencies() []*Component {
+	file, err := os.Open(filepath.Join(g.WorkDir, "go.sum"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var deps []*Component
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+
+		name, version := fields[0], fields[1]
+		if seen[name+"@"+version] {
+			continue
+		}
+		seen[name+"@"+version] = true
+		deps = append(deps, &Component{
+			Name:    name,
+			Version: version,
+			Type:    "library",
+			Purl:    purl("golang", name, version),
+		})
+	}
+	return deps
+}
+
 func (g *GoPack) Name() string {
 	return "golang"
 }
 
 func (g *GoPack) Command() (string, error) {
-	return "", nil
+	if g.Main != "" && !fileExists(g.WorkDir, g.Main) {
+		return "", fmt.Errorf("Unknown go main %s", g.Main)
+	}
+
+	mains := g.mains()
+	if mains[0] == "." {
+		return "", nil
+	}
+
+	name := filepath.Base(g.WorkDir)
+	if b, err := fileRead(g.WorkDir, "go.mod"); err == nil {
+		if module, err := getModule(b); err == nil {
+			name = path.Base(module)
+		}
+	}
+	for _, main := range mains {
+		if filepath.Base(main) == name {
+			return name, nil
+		}
+	}
+	if len(mains) > 1 {
+		return "", fmt.Errorf("Multiple go mains %s, set go_main to pick one", strings.Join(mains, " "))
+	}
+	return filepath.Base(mains[0]), nil
+}
+
+func (g *GoPack) requires() map[string]string {
+	b, err := fileRead(g.WorkDir, "go.mod")
+	if err != nil {
+		return nil
+	}
+
+	mod, err := gomod.Parse(b)
+	if err != nil {
+		return nil
+	}
+
+	requires := map[string]string{}
+	for _, require := range mod.Require {
+		requires[require.Path] = require.Version
+	}
+	return requires
 }
 
 func (g *GoPack) Version() (string, error) {
+	if _, version := toolVersion(g.WorkDir, "golang"); version != "" {
+		return version, nil
+	}
+
 	if fileExists(g.WorkDir, "go.mod") {
 		b, err := fileRead(g.WorkDir, "go.mod")
 		if err != nil {
@@ -104,3 +261,9 @@ func (g *GoPack) Version() (string, error) {
 	}
 	return "", nil
 }
+
+const cgoFormat = "-f '{{if and .CgoFiles (not .Standard)}}1{{end}}'"
+
+var (
+	goMainRegex = regexp.MustCompile(`(?m)^package\s+main\b`)
+)
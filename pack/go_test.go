@@ -0,0 +1,64 @@
+package pack
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoMetadata(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "golang", "tags": ["1.13", "1.21"]}`})
+
+	tests := []struct {
+		name    string
+		tags    string
+		mains   string
+		command string
+	}{
+		{name: "gomod", mains: " .", command: "gomod"},
+		{name: "cmd", tags: " -tags prod", mains: " ./cmd/migrate ./cmd/service", command: "service"},
+		{name: "cgo", mains: " .", command: "cgo"},
+	}
+	for _, test := range tests {
+		bp, err := Detect(filepath.Join("../testdata/go", test.name))
+		require.NoError(t, err, test.name)
+		require.Len(t, bp.Metadata.Install, 2, test.name)
+
+		cgo, install := bp.Metadata.Install[0], bp.Metadata.Install[1]
+		assert.Equal(t, `export CGO_ENABLED="${CGO_ENABLED:-$(go list -deps`+test.tags+" "+cgoFormat+test.mains+
+			` | grep -q 1 && echo 1 || echo 0)}"`, cgo, test.name)
+		assert.Equal(t, "go install -v -trimpath"+test.tags+` -ldflags "-s -w${GIT_VERSION:+ -X main.version=$GIT_VERSION}`+
+			`${GIT_COMMIT:+ -X main.commit=$GIT_COMMIT}"`+test.mains, install, test.name)
+		assert.Equal(t, test.command, bp.Metadata.Command, test.name)
+
+		df, err := bp.GetDockerfile()
+		require.NoError(t, err, test.name)
+		assert.Contains(t, df, "COPY --chown=web:web . ./\nARG GIT_COMMIT\nARG GIT_VERSION\nRUN export CGO_ENABLED", test.name)
+		assert.Regexp(t, `^[0-9a-f]{40}$`, bp.installArgs()["GIT_COMMIT"], test.name)
+		assert.NotContains(t, bp.Metadata.Env, "CGO_ENABLED", test.name)
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod":              "module example.com/app\n\ngo 1.21\n",
+		"cmd/migrate/main.go": "package main\n\nfunc main() {}\n",
+		"cmd/worker/main.go":  "package main\n\nfunc main() {}\n",
+	})
+	_, err := (&GoPack{WorkDir: dir}).Command()
+	assert.EqualError(t, err, "Multiple go mains ./cmd/migrate ./cmd/worker, set go_main to pick one")
+
+	command, err := (&GoPack{WorkDir: dir, Main: "cmd/worker"}).Command()
+	require.NoError(t, err)
+	assert.Equal(t, "worker", command)
+
+	dir = writeFiles(t, map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.21\n",
+		"cmd/server/main.go": "package main\n\nfunc main() {}\n",
+	})
+	command, err = (&GoPack{WorkDir: dir}).Command()
+	require.NoError(t, err)
+	assert.Equal(t, "server", command)
+}
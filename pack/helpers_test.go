@@ -0,0 +1,50 @@
+package pack
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubTransport map[string]string
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for pattern, body := range s {
+		if strings.Contains(req.URL.String(), pattern) {
+			status := http.StatusOK
+			if body == "" {
+				status = http.StatusNotFound
+			}
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}
+	}
+	return nil, errors.New("offline")
+}
+
+func stubHTTP(t *testing.T, stub stubTransport) {
+	rt := transport.rt
+	transport.rt = stub
+	t.Cleanup(func() { transport.rt = rt })
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		require.NoError(t, err)
+	}
+	return dir
+}
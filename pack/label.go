@@ -0,0 +1,97 @@
+package pack
+
+import (
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	labelBaseName  = "org.opencontainers.image.base.name"
+	labelCreated   = "org.opencontainers.image.created"
+	labelRevision  = "org.opencontainers.image.revision"
+	labelSource    = "org.opencontainers.image.source"
+	labelTitle     = "org.opencontainers.image.title"
+	labelVersion   = "org.opencontainers.image.version"
+	labelFramework = "io.lade.jet.framework"
+	labelPack      = "io.lade.jet.pack"
+	labelProcess   = "io.lade.jet.process."
+	labelRelease   = "io.lade.jet.release"
+	labelRuntime   = "io.lade.jet.runtime"
+)
+
+var (
+	sshRegex = regexp.MustCompile(`^(?:ssh://)?git@([^:/]+)[:/](.+)$`)
+	gitArgs  = map[string][]string{
+		"GIT_COMMIT":  {"rev-parse", "HEAD"},
+		"GIT_VERSION": {"describe", "--tags", "--always", "--dirty"},
+	}
+)
+
+func (b *Buildpack) Labels(name string) map[string]string {
+	labels := map[string]string{
+		labelBaseName: "docker.io/library/" + b.Metadata.Image,
+		labelCreated:  time.Now().UTC().Format(time.RFC3339),
+		labelTitle:    name,
+		labelPack:     b.Metadata.Name,
+		labelRuntime:  b.Metadata.Version,
+	}
+
+	if b.Metadata.Framework != "" {
+		labels[labelFramework] = b.Metadata.Framework
+	}
+	if b.Metadata.Release != "" {
+		labels[labelRelease] = b.Metadata.Release
+	}
+	for name, command := range b.Metadata.Processes {
+		labels[labelProcess+name] = command
+	}
+
+	if source := gitOutput(b.WorkDir, "config", "--get", "remote.origin.url"); source != "" {
+		labels[labelSource] = gitSource(source)
+	}
+	if revision := gitOutput(b.WorkDir, "rev-parse", "HEAD"); revision != "" {
+		labels[labelRevision] = revision
+	}
+	if version := gitOutput(b.WorkDir, "describe", "--tags", "--always", "--dirty"); version != "" {
+		labels[labelVersion] = version
+	}
+
+	if b.Config != nil {
+		for key, value := range b.Config.Labels {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+func (b *Buildpack) installArgs() map[string]string {
+	args := map[string]string{}
+	for _, key := range b.Metadata.InstallArgs {
+		if value := gitOutput(b.WorkDir, gitArgs[key]...); value != "" {
+			args[key] = value
+		}
+	}
+	return args
+}
+
+func gitOutput(dir string, args ...string) string {
+	args = append([]string{"-C", dir}, args...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func gitSource(source string) string {
+	if matches := sshRegex.FindStringSubmatch(source); len(matches) > 2 {
+		source = "https://" + matches[1] + "/" + matches[2]
+	} else if u, err := url.Parse(source); err == nil && u.User != nil {
+		u.User = nil
+		source = u.String()
+	}
+	return strings.TrimSuffix(source, ".git")
+}
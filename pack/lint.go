@@ -0,0 +1,201 @@
+package pack
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+type Issue struct {
+	Line     int
+	Rule     string
+	Severity string
+	Message  string
+}
+
+type instruction struct {
+	line int
+	cmd  string
+	args string
+}
+
+var (
+	aptCleanRegex   = regexp.MustCompile(`rm -rf /var/lib/apt/lists`)
+	aptInstallRegex = regexp.MustCompile(`apt-get\s+(?:-\S+\s+)*install`)
+	depInstallRegex = regexp.MustCompile(`\b(?:npm (?:install|ci)|yarn(?: install)?(?:\s*&&|\s*$)|pnpm install|pip install -r|bundle install|composer install|go mod download|poetry install)`)
+	downloadRegex   = regexp.MustCompile(`(?:wget|curl)\s[^|&;]*(https?://\S+)`)
+	pipeShellRegex  = regexp.MustCompile(`(?:wget|curl)\s[^&;]*\|\s*(?:sudo\s+)?(?:ba)?sh\b`)
+	pruneRegex      = regexp.MustCompile(`--omit=dev|--production|--prod\b`)
+	secretRegex     = regexp.MustCompile(`(?i)(^|_)(password|passwd|secret|token|api_?key|private_?key|access_?key|credentials)(_|$)`)
+)
+
+func (b *Buildpack) Lint() ([]*Issue, error) {
+	dockerfile, err := b.GetDockerfile()
+	if err != nil {
+		return nil, err
+	}
+	return Lint(dockerfile), nil
+}
+
+func LintFile(file string) ([]*Issue, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(string(b)), nil
+}
+
+func Lint(dockerfile string) []*Issue {
+	var issues []*Issue
+	report := func(line int, rule, severity, message string) {
+		issues = append(issues, &Issue{
+			Line:     line,
+			Rule:     rule,
+			Severity: severity,
+			Message:  message,
+		})
+	}
+
+	var user *instruction
+	var copyAll *instruction
+	from := 0
+	for _, inst := range parseDockerfile(dockerfile) {
+		switch inst.cmd {
+		case "FROM":
+			from = inst.line
+			user = nil
+			copyAll = nil
+		case "USER":
+			user = inst
+		case "ENV", "ARG":
+			for _, key := range instructionKeys(inst.args) {
+				if secretRegex.MatchString(key) {
+					report(inst.line, "env-secret", SeverityError,
+						inst.cmd+" "+key+" looks like a secret, pass it at runtime or as a build secret")
+				}
+			}
+		case "ADD":
+			if strings.Contains(inst.args, "http://") || strings.Contains(inst.args, "https://") {
+				report(inst.line, "unpinned-download", SeverityWarning,
+					"ADD of a remote URL is not verified, download with a pinned version and checksum")
+			}
+			fallthrough
+		case "COPY":
+			if copiesContext(inst.args) && copyAll == nil {
+				copyAll = inst
+			}
+		case "RUN":
+			if aptInstallRegex.MatchString(inst.args) {
+				if !strings.Contains(inst.args, "--no-install-recommends") {
+					report(inst.line, "apt-no-recommends", SeverityWarning,
+						"apt-get install without --no-install-recommends installs unneeded packages")
+				}
+				if !aptCleanRegex.MatchString(inst.args) {
+					report(inst.line, "apt-cache", SeverityWarning,
+						"apt-get install leaves the package lists behind, remove /var/lib/apt/lists/* in the same RUN")
+				}
+			}
+
+			if pipeShellRegex.MatchString(inst.args) {
+				report(inst.line, "unpinned-download", SeverityWarning,
+					"Downloaded script is piped into a shell without verification")
+			}
+			for _, matches := range downloadRegex.FindAllStringSubmatch(inst.args, -1) {
+				if strings.Contains(matches[1], "latest") {
+					report(inst.line, "unpinned-download", SeverityWarning,
+						"Download of "+matches[1]+" is not pinned to a version")
+				}
+			}
+
+			if copyAll != nil && depInstallRegex.MatchString(inst.args) && !pruneRegex.MatchString(inst.args) {
+				report(copyAll.line, "copy-before-install", SeverityWarning,
+					"Full build context is copied before installing dependencies, which defeats layer caching")
+				copyAll = nil
+			}
+		}
+	}
+
+	if from > 0 && (user == nil || user.args == "root" || user.args == "0" || strings.HasPrefix(user.args, "root:") || strings.HasPrefix(user.args, "0:")) {
+		line := from
+		if user != nil {
+			line = user.line
+		}
+		report(line, "root-user", SeverityError, "Container runs as root, add a USER instruction for an unprivileged user")
+	}
+	return issues
+}
+
+func copiesContext(args string) bool {
+	fields := []string{}
+	for _, field := range strings.Fields(args) {
+		if !strings.HasPrefix(field, "--") {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) < 2 {
+		return false
+	}
+
+	for _, src := range fields[:len(fields)-1] {
+		if src == "." || src == "./" {
+			return true
+		}
+	}
+	return false
+}
+
+func instructionKeys(args string) []string {
+	var keys []string
+	fields := strings.Fields(args)
+	if len(fields) > 1 && !strings.Contains(fields[0], "=") {
+		return []string{fields[0]}
+	}
+	for _, field := range fields {
+		if key, _, ok := strings.Cut(field, "="); ok || len(fields) == 1 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func parseDockerfile(dockerfile string) []*instruction {
+	var insts []*instruction
+	var current *instruction
+	for i, line := range strings.Split(dockerfile, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current == nil {
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			cmd, args, _ := strings.Cut(trimmed, " ")
+			current = &instruction{
+				line: i + 1,
+				cmd:  strings.ToUpper(cmd),
+			}
+			trimmed = args
+		} else if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		continued := strings.HasSuffix(trimmed, "\\")
+		trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, "\\"))
+		if current.args != "" && trimmed != "" {
+			current.args += " "
+		}
+		current.args += trimmed
+
+		if !continued {
+			insts = append(insts, current)
+			current = nil
+		}
+	}
+	if current != nil {
+		insts = append(insts, current)
+	}
+	return insts
+}
@@ -0,0 +1,77 @@
+package pack_test
+
+import (
+	"testing"
+
+	"github.com/lade-io/jet/pack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	dockerfile := `FROM node:18
+ENV API_TOKEN=abc123
+RUN apt-get update && apt-get install -y \
+	libpq-dev
+RUN curl -fsSL https://example.com/latest/install.sh | sh
+WORKDIR /app
+COPY . ./
+RUN npm ci
+CMD ["node", "server.js"]
+`
+	rules := map[string]int{}
+	for _, issue := range pack.Lint(dockerfile) {
+		rules[issue.Rule] = issue.Line
+	}
+
+	assert.Equal(t, map[string]int{
+		"env-secret":          2,
+		"apt-no-recommends":   3,
+		"apt-cache":           3,
+		"unpinned-download":   5,
+		"copy-before-install": 7,
+		"root-user":           1,
+	}, rules)
+}
+
+func TestLintClean(t *testing.T) {
+	dockerfile := `FROM python:3.11
+RUN set -ex \
+	&& apt-get update && apt-get install -y --no-install-recommends \
+		libpq-dev \
+	&& rm -rf /var/lib/apt/lists/*
+USER web
+WORKDIR /home/web/app/
+COPY --chown=web:web requirements.txt ./
+RUN pip install -r requirements.txt
+COPY --chown=web:web . ./
+CMD ["gunicorn", "app:app"]
+`
+	assert.Empty(t, pack.Lint(dockerfile))
+}
+
+func TestLintSecrets(t *testing.T) {
+	tests := map[string]bool{
+		"API_TOKEN":              true,
+		"DB_PASSWORD":            true,
+		"SECRET_KEY_BASE":        true,
+		"STRIPE_API_KEY":         true,
+		"AWS_ACCESS_KEY_ID":      true,
+		"github_token":           true,
+		"TOKENIZERS_PARALLELISM": false,
+		"PRIMARY_KEY_FIELD":      false,
+		"SECRETARY_EMAIL":        false,
+		"PASSWORDLESS_LOGIN":     false,
+		"NODE_ENV":               false,
+	}
+	for key, secret := range tests {
+		var rules []string
+		for _, issue := range pack.Lint("FROM node:18\nUSER node\nARG " + key + "\n") {
+			rules = append(rules, issue.Rule)
+		}
+		if secret {
+			assert.Equal(t, []string{"env-secret"}, rules, key)
+		} else {
+			assert.Empty(t, rules, key)
+		}
+	}
+}
@@ -1,14 +1,25 @@
 package pack
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/aquasecurity/go-version/pkg/version"
+	"github.com/bmatcuk/doublestar"
+	"gopkg.in/yaml.v3"
 )
 
 type NodePack struct {
 	WorkDir string
+	Rules   *Rules
 }
 
 func (n *NodePack) Detect() bool {
@@ -18,18 +29,53 @@ func (n *NodePack) Detect() bool {
 func (n *NodePack) Metadata() *Metadata {
 	user := "node"
 	meta := &Metadata{
-		Env: map[string]string{
-			"PATH": "/home/" + user + "/app/node_modules/.bin:$PATH",
-		},
+		Bin:  []string{"/home/" + user + "/app/node_modules/.bin"},
+		Env:  map[string]string{},
 		User: user,
 	}
 
-	if fileExists(n.WorkDir, "yarn.lock") {
+	_, devDeps := n.dependencies()
+	manager, pinned := n.packageManager()
+	if pinned != "" && manager != "npm" {
+		meta.Env["COREPACK_ENABLE_DOWNLOAD_PROMPT"] = "0"
+	}
+
+	if manager == "pnpm" {
+		install := "install"
+		if fileExists(n.WorkDir, "pnpm-lock.yaml") {
+			install = "install --frozen-lockfile"
+		}
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:    "pnpm",
+			Files:   append(n.manifests(), "pnpm-lock.yaml", "pnpm-workspace.yaml"),
+			Install: []string{install},
+			Hook:    n.download,
+		})
+		meta.Prune = []string{"pnpm prune --prod"}
+	} else if manager == "yarn" && n.yarnBerry(pinned) {
+		install := []string{"install"}
+		if fileExists(n.WorkDir, "yarn.lock") {
+			install = []string{"install --immutable"}
+		}
 		meta.Tools = append(meta.Tools, &Tool{
 			Name:    "yarn",
-			Files:   []string{"**/package.json", "yarn.lock"},
-			Install: []string{"install"},
+			Files:   append(n.manifests(), "yarn.lock", ".yarnrc.yml", ".yarn/releases/*", ".yarn/plugins/**/*"),
+			Install: install,
+			Hook:    n.yarnBerrySetup,
+		})
+		meta.Prune = []string{"yarn workspaces focus --all --production"}
+	} else if manager == "yarn" {
+		install := "install"
+		if fileExists(n.WorkDir, "yarn.lock") {
+			install = "install --frozen-lockfile"
+		}
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:    "yarn",
+			Files:   append(n.manifests(), "yarn.lock"),
+			Install: []string{install},
+			Hook:    n.download,
 		})
+		meta.Prune = []string{"yarn " + install + " --production"}
 	} else {
 		meta.Tools = append(meta.Tools, &Tool{
 			Name:    "npm",
@@ -40,7 +86,12 @@ func (n *NodePack) Metadata() *Metadata {
 					return nil
 				}
 
-				constraints, err := version.NewConstraints("^8.12 || >=10.3")
+				constraints, err := version.NewConstraints(n.Rules.orDefault().Node.NpmCI)
+				if err != nil {
+					return err
+				}
+
+				omitDev, err := version.NewConstraints(n.Rules.orDefault().Node.NpmOmitDev)
 				if err != nil {
 					return err
 				}
@@ -49,9 +100,19 @@ func (n *NodePack) Metadata() *Metadata {
 				if constraints.Check(v) {
 					tool.Install = []string{"ci"}
 				}
+				if constraints.Check(v) && omitDev.Check(v) && meta.Prune != nil {
+					meta.Prune = []string{"npm ci --omit=dev"}
+				}
 				return nil
 			},
 		})
+		meta.Prune = []string{"npm prune --production"}
+	}
+
+	if len(devDeps) > 0 {
+		meta.RunEnv = map[string]string{"NODE_ENV": "production"}
+	} else {
+		meta.Prune = nil
 	}
 
 	scripts := n.scripts()
@@ -61,6 +122,69 @@ func (n *NodePack) Metadata() *Metadata {
 	return meta
 }
 
+func (n *NodePack) download(meta *Metadata, tool *Tool) error {
+	manager, pinned := n.packageManager()
+	node, _ := version.Parse(meta.Version)
+	if pinned != "" {
+		bundled, err := version.NewConstraints(n.Rules.orDefault().Node.CorepackBundled)
+		if err != nil {
+			return err
+		}
+
+		if corepack := nodeTool(n.Rules.orDefault().Node.Corepack, node); corepack != "" {
+			tool.Download = "npm install -g corepack@" + corepack + " && corepack enable"
+		} else if bundled.Check(node) {
+			tool.Download = "corepack enable"
+		} else if manager == "pnpm" {
+			tool.Download = "npm install -g pnpm@" + pinned
+		} else if manager == "yarn" && n.yarnBerry(pinned) {
+			tool.Install = append([]string{"set version " + pinned}, tool.Install...)
+		}
+	} else if manager == "pnpm" {
+		pnpm := pnpmMajors[n.pnpmLockVersion()]
+		if pnpm == "" {
+			pnpm = nodeTool(n.Rules.orDefault().Node.Pnpm, node)
+		}
+		tool.Download = "npm install -g pnpm"
+		if pnpm != "" {
+			tool.Download += "@" + pnpm
+		}
+	}
+	return nil
+}
+
+func (n *NodePack) yarnBerrySetup(meta *Metadata, tool *Tool) error {
+	_, release := n.packageManager()
+	if release == "" {
+		b, _ := fileRead(n.WorkDir, ".yarnrc.yml")
+		if matches := yarnPathRegex.FindSubmatch(b); len(matches) > 1 {
+			release = string(matches[1])
+		} else if !bytes.Contains(b, []byte("yarnPath:")) {
+			node, _ := version.Parse(meta.Version)
+			if release = nodeTool(n.Rules.orDefault().Node.Yarn, node); release != "" {
+				tool.Install = append([]string{"set version " + release}, tool.Install...)
+			}
+		}
+	}
+
+	major, _, _ := strings.Cut(release, ".")
+	if (major == "2" || major == "3") && meta.Prune != nil &&
+		!fileContains(n.WorkDir, ".yarnrc.yml", "plugin-workspace-tools") {
+		meta.Prune = append([]string{"yarn plugin import workspace-tools"}, meta.Prune...)
+	}
+	return n.download(meta, tool)
+}
+
+func nodeTool(tools []*NodeTool, node version.Version) string {
+	for _, tool := range tools {
+		constraints, err := version.NewConstraints(tool.Node)
+		if err == nil && constraints.Check(node) {
+			return tool.Version
+		}
+	}
+	return ""
+}
+
 func (n *NodePack) Name() string {
 	return "node"
 }
@@ -76,9 +200,14 @@ func (n *NodePack) Command() (string, error) {
 		return "", err
 	}
 
+	run := ""
+	if n.yarnPnP() {
+		run = "yarn "
+	}
+
 	main, ok := conf["main"].(string)
 	if ok {
-		return "node " + main, nil
+		return run + "node " + main, nil
 	}
 
 	scripts, ok := conf["scripts"].(map[string]interface{})
@@ -87,42 +216,383 @@ func (n *NodePack) Command() (string, error) {
 	}
 
 	start, ok := scripts["start"].(string)
-	if ok {
+	if ok && run != "" {
+		return run + "start", nil
+	} else if ok {
 		return start, nil
 	}
 	return "", nil
 }
 
 func (n *NodePack) Version() (string, error) {
-	for _, file := range []string{"package.json", ".nvmrc"} {
+	_, constraint := n.versionSource()
+	return constraint, nil
+}
+
+func (n *NodePack) resolveVersion(constraint string) (string, error) {
+	if constraint == "" {
+		return "", nil
+	}
+
+	resolved, err := resolveNodeVersion(constraint)
+	if err != nil {
+		source, _ := n.versionSource()
+		return "", fmt.Errorf("Unknown node version %s in %s: %v", constraint, source, err)
+	}
+	return resolved, nil
+}
+
+func (n *NodePack) versionSource() (string, string) {
+	conf := struct {
+		Engines map[string]interface{} `json:"engines"`
+		Volta   map[string]interface{} `json:"volta"`
+	}{}
+	if b, err := fileRead(n.WorkDir, "package.json"); err == nil {
+		json.Unmarshal(b, &conf)
+	}
+
+	if node, ok := conf.Volta["node"].(string); ok && node != "" {
+		return "package.json volta", node
+	}
+
+	for _, file := range []string{".nvmrc", ".node-version"} {
 		b, err := fileRead(n.WorkDir, file)
 		if err != nil {
 			continue
 		}
 
-		if file == ".nvmrc" {
-			return string(b), nil
+		for _, line := range strings.Split(string(b), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			if line = strings.TrimSpace(line); line != "" {
+				return file, line
+			}
+		}
+	}
+
+	if source, node := toolVersion(n.WorkDir, "node"); node != "" {
+		return source, strings.Replace(node, "lts-", "lts/", 1)
+	}
+
+	if node, ok := conf.Engines["node"].(string); ok && node != "" {
+		return "package.json engines", strings.ReplaceAll(node, "~>", "~")
+	}
+	return "", ""
+}
+
+func resolveNodeVersion(constraint string) (string, error) {
+	versions, err := getNodeVersions()
+	if err != nil {
+		return "", err
+	}
+
+	alias := strings.ToLower(strings.TrimPrefix(constraint, "v"))
+	for _, node := range versions {
+		release := strings.TrimPrefix(node.Version, "v")
+		switch {
+		case alias == "node" || alias == "latest" || alias == "current" || alias == "stable":
+			return release, nil
+		case alias == "lts/*" || alias == "lts":
+			if node.IsLTS() {
+				return release, nil
+			}
+		case strings.HasPrefix(alias, "lts/"):
+			if node.Codename() == strings.TrimPrefix(alias, "lts/") {
+				return release, nil
+			}
+		}
+	}
+
+	if strings.HasPrefix(alias, "lts") || strings.IndexFunc(alias, unicode.IsLetter) == 0 {
+		return "", fmt.Errorf("unknown alias")
+	}
+
+	constraints, err := version.NewConstraints(alias)
+	if err != nil {
+		return "", err
+	}
+
+	for _, node := range versions {
+		parts := strings.Split(strings.TrimPrefix(node.Version, "v"), ".")
+		for i := range parts {
+			v, err := version.Parse(strings.Join(parts[:i+1], "."))
+			if err == nil && constraints.Check(v) {
+				return alias, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no release satisfies the constraint")
+}
+
+func (n *NodePack) Dependencies() []*Component {
+	var deps []*Component
+	seen := map[string]bool{}
+	add := func(name, version string) {
+		if seen[name+"@"+version] {
+			return
+		}
+		seen[name+"@"+version] = true
+		deps = append(deps, &Component{
+			Name:    name,
+			Version: version,
+			Type:    "library",
+			Purl:    purl("npm", name, version),
+		})
+	}
+
+	if fileExists(n.WorkDir, "pnpm-lock.yaml") {
+		n.pnpmVersions(add)
+	} else if fileExists(n.WorkDir, "yarn.lock") {
+		n.yarnVersions(add)
+	} else {
+		n.npmVersions(add)
+	}
+	return deps
+}
+
+type npmDependency struct {
+	Version      string                    `json:"version"`
+	Dependencies map[string]*npmDependency `json:"dependencies"`
+}
+
+func (n *NodePack) npmVersions(add func(name, version string)) {
+	b, err := fileRead(n.WorkDir, "package-lock.json")
+	if err != nil {
+		return
+	}
+
+	lock := struct {
+		Packages     map[string]*npmDependency `json:"packages"`
+		Dependencies map[string]*npmDependency `json:"dependencies"`
+	}{}
+	if err = json.Unmarshal(b, &lock); err != nil {
+		return
+	}
+
+	for path, dep := range lock.Packages {
+		i := strings.LastIndex(path, "node_modules/")
+		if i < 0 || dep.Version == "" {
+			continue
+		}
+		add(path[i+len("node_modules/"):], dep.Version)
+	}
+
+	var walk func(deps map[string]*npmDependency)
+	walk = func(deps map[string]*npmDependency) {
+		for name, dep := range deps {
+			if dep.Version != "" {
+				add(name, dep.Version)
+			}
+			walk(dep.Dependencies)
 		}
+	}
+	if len(lock.Packages) == 0 {
+		walk(lock.Dependencies)
+	}
+}
 
-		conf := map[string]interface{}{}
-		if err = json.Unmarshal(b, &conf); err != nil {
-			return "", err
+func (n *NodePack) pnpmVersions(add func(name, version string)) {
+	file, err := os.Open(filepath.Join(n.WorkDir, "pnpm-lock.yaml"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	packages := false
+	entryRegex := pnpmEntryRegex
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := pnpmLockRegex.FindStringSubmatch(line); len(matches) > 1 {
+			if major, _, _ := strings.Cut(matches[1], "."); len(major) == 1 && major < "6" {
+				entryRegex = pnpmLegacyRegex
+			}
 		}
 
-		engines, ok := conf["engines"].(map[string]interface{})
-		if !ok {
+		if strings.TrimSpace(line) == "" {
 			continue
+		} else if !strings.HasPrefix(line, " ") {
+			packages = strings.TrimSpace(line) == "packages:"
+		} else if matches := entryRegex.FindStringSubmatch(line); packages && len(matches) > 2 {
+			add(matches[1], matches[2])
+		}
+	}
+}
+
+func (n *NodePack) pnpmLockVersion() string {
+	file, err := os.Open(filepath.Join(n.WorkDir, "pnpm-lock.yaml"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if matches := pnpmLockRegex.FindStringSubmatch(scanner.Text()); len(matches) > 1 {
+			major, _, _ := strings.Cut(matches[1], ".")
+			return major
+		}
+	}
+	return ""
+}
+
+func (n *NodePack) yarnVersions(add func(name, version string)) {
+	file, err := os.Open(filepath.Join(n.WorkDir, "yarn.lock"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	name := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := yarnEntryRegex.FindStringSubmatch(line); len(matches) > 1 {
+			name = matches[1]
+		} else if matches := yarnVersionRegex.FindStringSubmatch(line); len(matches) > 1 && name != "" {
+			add(name, matches[1])
+			name = ""
+		}
+	}
+}
+
+func (n *NodePack) manifests() []string {
+	var patterns []string
+	conf := struct {
+		Workspaces interface{} `json:"workspaces"`
+	}{}
+	if b, err := fileRead(n.WorkDir, "package.json"); err == nil {
+		json.Unmarshal(b, &conf)
+	}
+
+	workspaces, _ := conf.Workspaces.([]interface{})
+	if workspace, ok := conf.Workspaces.(map[string]interface{}); ok {
+		workspaces, _ = workspace["packages"].([]interface{})
+	}
+	for _, pattern := range workspaces {
+		if pattern, ok := pattern.(string); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	if b, err := fileRead(n.WorkDir, "pnpm-workspace.yaml"); err == nil {
+		workspace := struct {
+			Packages []string `yaml:"packages"`
+		}{}
+		if err = yaml.Unmarshal(b, &workspace); err == nil {
+			patterns = append(patterns, workspace.Packages...)
+		}
+	}
+
+	var excludes []string
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			excludes = append(excludes, path.Clean(strings.TrimPrefix(pattern, "!")))
 		}
+	}
 
-		node, ok := engines["node"].(string)
-		if !ok {
+	manifests := []string{"package.json"}
+	seen := map[string]bool{"package.json": true}
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
 			continue
 		}
 
-		node = strings.ReplaceAll(node, "~>", "~")
-		return node, nil
+		paths, _ := fileGlob(n.WorkDir, path.Join(path.Clean(pattern), "package.json"))
+		for _, manifest := range paths {
+			dir := filepath.ToSlash(filepath.Dir(manifest))
+			if seen[manifest] || strings.Contains("/"+dir+"/", "/node_modules/") {
+				continue
+			}
+
+			excluded := false
+			for _, exclude := range excludes {
+				if ok, _ := doublestar.Match(exclude, dir); ok {
+					excluded = true
+				}
+			}
+			if !excluded {
+				manifests = append(manifests, manifest)
+			}
+			seen[manifest] = true
+		}
 	}
-	return "", nil
+	return manifests
+}
+
+func (n *NodePack) dependencies() (map[string]bool, map[string]bool) {
+	b, err := fileRead(n.WorkDir, "package.json")
+	if err != nil {
+		return nil, nil
+	}
+
+	conf := struct {
+		Dependencies    map[string]interface{} `json:"dependencies"`
+		DevDependencies map[string]interface{} `json:"devDependencies"`
+	}{}
+	if err = json.Unmarshal(b, &conf); err != nil {
+		return nil, nil
+	}
+
+	deps := map[string]bool{}
+	for name := range conf.Dependencies {
+		deps[name] = true
+	}
+	devDeps := map[string]bool{}
+	for name := range conf.DevDependencies {
+		devDeps[name] = true
+	}
+	return deps, devDeps
+}
+
+func (n *NodePack) packages() map[string]bool {
+	deps, devDeps := n.dependencies()
+	packages := map[string]bool{}
+	for name := range deps {
+		packages[name] = true
+	}
+	for name := range devDeps {
+		packages[name] = true
+	}
+	return packages
+}
+
+func (n *NodePack) packageManager() (string, string) {
+	conf := struct {
+		PackageManager string `json:"packageManager"`
+	}{}
+	if b, err := fileRead(n.WorkDir, "package.json"); err == nil {
+		json.Unmarshal(b, &conf)
+	}
+
+	if name, pinned, ok := strings.Cut(conf.PackageManager, "@"); ok {
+		pinned, _, _ = strings.Cut(pinned, "+")
+		return name, pinned
+	}
+
+	switch {
+	case fileExists(n.WorkDir, "pnpm-lock.yaml"):
+		return "pnpm", ""
+	case fileExists(n.WorkDir, "yarn.lock") || fileExists(n.WorkDir, ".yarnrc.yml"):
+		return "yarn", ""
+	}
+	return "npm", ""
+}
+
+func (n *NodePack) yarnBerry(pinned string) bool {
+	if pinned != "" {
+		return !strings.HasPrefix(pinned, "1.")
+	}
+	return fileExists(n.WorkDir, ".yarnrc.yml") ||
+		fileContains(n.WorkDir, "yarn.lock", "__metadata:")
+}
+
+func (n *NodePack) yarnPnP() bool {
+	manager, pinned := n.packageManager()
+	if manager != "yarn" || !n.yarnBerry(pinned) {
+		return false
+	}
+	return !fileContains(n.WorkDir, ".yarnrc.yml", "nodeLinker: node-modules") &&
+		!fileContains(n.WorkDir, ".yarnrc.yml", "nodeLinker: pnpm")
 }
 
 func (n *NodePack) scripts() map[string]bool {
@@ -149,3 +619,19 @@ func (n *NodePack) scripts() map[string]bool {
 	}
 	return scriptMap
 }
+
+var pnpmMajors = map[string]string{
+	"5": "7",
+	"6": "8",
+	"7": "9",
+	"9": "9",
+}
+
+var (
+	pnpmEntryRegex   = regexp.MustCompile(`^  '?/?(@?[^@\s']+)@([^(:\s']+)`)
+	pnpmLegacyRegex  = regexp.MustCompile(`^  '?/((?:@[^/\s']+/)?[^/\s']+)/([^/_(:\s']+)`)
+	pnpmLockRegex    = regexp.MustCompile(`^lockfileVersion:\s*['"]?([\d.]+)`)
+	yarnPathRegex    = regexp.MustCompile(`(?m)^yarnPath:\s*['"]?\S*yarn-(\d[\w.-]*)\.c?js`)
+	yarnEntryRegex   = regexp.MustCompile(`^"?(@?[^@"\s]+)@`)
+	yarnVersionRegex = regexp.MustCompile(`^\s+version:?\s+"?([^"\s]+)"?`)
+)
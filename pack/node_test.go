@@ -0,0 +1,278 @@
+package pack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodeIndex = `[
+	{"version": "v21.1.0", "lts": false},
+	{"version": "v20.9.0", "lts": "Iron"},
+	{"version": "v18.18.2", "lts": "Hydrogen"},
+	{"version": "v16.20.2", "lts": "Gallium"}
+]`
+
+func TestResolveNodeVersion(t *testing.T) {
+	stubHTTP(t, stubTransport{"nodejs.org/dist/index.json": nodeIndex})
+
+	tests := []struct {
+		constraint string
+		expected   string
+		err        bool
+	}{
+		{constraint: "node", expected: "21.1.0"},
+		{constraint: "lts/*", expected: "20.9.0"},
+		{constraint: "lts/hydrogen", expected: "18.18.2"},
+		{constraint: "v18", expected: "18"},
+		{constraint: ">=16 <18", expected: ">=16 <18"},
+		{constraint: "lts/argon", err: true},
+		{constraint: "99", err: true},
+	}
+	for _, test := range tests {
+		resolved, err := resolveNodeVersion(test.constraint)
+		if test.err {
+			assert.Error(t, err, test.constraint)
+			continue
+		}
+		require.NoError(t, err, test.constraint)
+		assert.Equal(t, test.expected, resolved, test.constraint)
+	}
+}
+
+func TestDetectNodeIndexOffline(t *testing.T) {
+	stubHTTP(t, stubTransport{"/tags/list": `{"name": "golang", "tags": ["1.20", "1.21"]}`})
+
+	dir := writeFiles(t, map[string]string{
+		"go.mod":       "module example.com/app\n\ngo 1.21\n",
+		"main.go":      "package main\n\nfunc main() {}\n",
+		"package.json": `{"engines": {"node": "lts/*"}}`,
+	})
+	bp, err := DetectConfig(dir, &Config{})
+	require.NoError(t, err)
+	assert.Equal(t, "golang", bp.Metadata.Name)
+
+	dir = writeFiles(t, map[string]string{
+		"package.json": `{"engines": {"node": "lts/*"}}`,
+	})
+	_, err = DetectConfig(dir, &Config{})
+	assert.ErrorContains(t, err, "Unknown node version lts/* in package.json engines")
+}
+
+func TestPnpmVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		lock string
+	}{
+		{
+			name: "v5",
+			lock: `lockfileVersion: 5.4
+
+specifiers:
+  '@babel/core': ^7.22.0
+  react-dom: ^18.2.0
+
+packages:
+
+  /@babel/core/7.22.5:
+    resolution: {integrity: sha512-abc}
+    dev: false
+
+  /react-dom/18.2.0_react@18.2.0:
+    resolution: {integrity: sha512-def}
+    dev: false
+`,
+		},
+		{
+			name: "v6",
+			lock: `lockfileVersion: '6.0'
+
+dependencies:
+  react-dom:
+    specifier: ^18.2.0
+    version: 18.2.0(react@18.2.0)
+
+packages:
+
+  /@babel/core@7.22.5:
+    resolution: {integrity: sha512-abc}
+    dev: false
+
+  /react-dom@18.2.0(react@18.2.0):
+    resolution: {integrity: sha512-def}
+    dev: false
+`,
+		},
+		{
+			name: "v9",
+			lock: `lockfileVersion: '9.0'
+
+packages:
+
+  '@babel/core@7.22.5':
+    resolution: {integrity: sha512-abc}
+
+  react-dom@18.2.0:
+    resolution: {integrity: sha512-def}
+`,
+		},
+	}
+	for _, test := range tests {
+		dir := writeFiles(t, map[string]string{"pnpm-lock.yaml": test.lock})
+
+		var deps []string
+		(&NodePack{WorkDir: dir}).pnpmVersions(func(name, version string) {
+			deps = append(deps, name+"@"+version)
+		})
+		assert.Equal(t, []string{"@babel/core@7.22.5", "react-dom@18.2.0"}, deps, test.name)
+	}
+}
+
+func TestNodeDownload(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		node     string
+		expected string
+		install  []string
+	}{
+		{
+			name:     "pnpm v5 lockfile",
+			files:    map[string]string{"pnpm-lock.yaml": "lockfileVersion: 5.4\n"},
+			node:     "20.9.0",
+			expected: "npm install -g pnpm@7",
+		},
+		{
+			name:     "pnpm v6 lockfile",
+			files:    map[string]string{"pnpm-lock.yaml": "lockfileVersion: '6.0'\n"},
+			node:     "20.9.0",
+			expected: "npm install -g pnpm@8",
+		},
+		{
+			name:     "pnpm v9 lockfile",
+			files:    map[string]string{"pnpm-lock.yaml": "lockfileVersion: '9.0'\n"},
+			node:     "20.9.0",
+			expected: "npm install -g pnpm@9",
+		},
+		{
+			name:     "pnpm old node",
+			files:    map[string]string{"package.json": `{"packageManager": "pnpm"}`, "pnpm-lock.yaml": "{}\n"},
+			node:     "16.20.2",
+			expected: "npm install -g pnpm@8",
+		},
+		{
+			name:     "corepack",
+			files:    map[string]string{"package.json": `{"packageManager": "pnpm@8.15.0"}`},
+			node:     "20.10.0",
+			expected: "npm install -g corepack@0.31.0 && corepack enable",
+		},
+		{
+			name:     "bundled corepack",
+			files:    map[string]string{"package.json": `{"packageManager": "yarn@3.6.4"}`},
+			node:     "16.20.2",
+			expected: "corepack enable",
+		},
+		{
+			name:     "backported corepack",
+			files:    map[string]string{"package.json": `{"packageManager": "pnpm@7.33.0"}`},
+			node:     "14.21.3",
+			expected: "corepack enable",
+		},
+		{
+			name:     "pnpm without corepack",
+			files:    map[string]string{"package.json": `{"packageManager": "pnpm@6.35.1+sha256.abc"}`},
+			node:     "14.17.6",
+			expected: "npm install -g pnpm@6.35.1",
+		},
+		{
+			name:    "yarn berry without corepack",
+			files:   map[string]string{"package.json": `{"packageManager": "yarn@3.6.4"}`},
+			node:    "12.22.12",
+			install: []string{"set version 3.6.4"},
+		},
+		{
+			name:  "yarn classic without corepack",
+			files: map[string]string{"package.json": `{"packageManager": "yarn@1.22.19"}`},
+			node:  "12.22.12",
+		},
+		{
+			name:  "yarn classic",
+			files: map[string]string{"yarn.lock": "# yarn lockfile v1\n"},
+			node:  "20.9.0",
+		},
+	}
+	for _, test := range tests {
+		if _, ok := test.files["package.json"]; !ok {
+			test.files["package.json"] = `{"name": "app"}`
+		}
+		dir := writeFiles(t, test.files)
+
+		tool := &Tool{}
+		err := (&NodePack{WorkDir: dir}).download(&Metadata{Version: test.node}, tool)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, tool.Download, test.name)
+		assert.Equal(t, test.install, tool.Install, test.name)
+	}
+}
+
+func TestYarnBerrySetup(t *testing.T) {
+	focus := "yarn workspaces focus --all --production"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		node    string
+		install []string
+		prune   []string
+	}{
+		{
+			name:    "yarn 3",
+			files:   map[string]string{"package.json": `{"packageManager": "yarn@3.6.4"}`},
+			node:    "20.9.0",
+			install: []string{"install"},
+			prune:   []string{"yarn plugin import workspace-tools", focus},
+		},
+		{
+			name:    "yarn 4",
+			files:   map[string]string{"package.json": `{"packageManager": "yarn@4.1.0"}`},
+			node:    "20.9.0",
+			install: []string{"install"},
+			prune:   []string{focus},
+		},
+		{
+			name: "yarn path with plugin",
+			files: map[string]string{
+				"package.json": `{"name": "app"}`,
+				".yarnrc.yml": "yarnPath: .yarn/releases/yarn-3.6.4.cjs\nplugins:\n" +
+					"  - path: .yarn/plugins/@yarnpkg/plugin-workspace-tools.cjs\n",
+			},
+			node:    "20.9.0",
+			install: []string{"install"},
+			prune:   []string{focus},
+		},
+		{
+			name:    "unpinned",
+			files:   map[string]string{"package.json": `{"name": "app"}`, ".yarnrc.yml": "nodeLinker: node-modules\n"},
+			node:    "20.9.0",
+			install: []string{"set version 4.5.3", "install"},
+			prune:   []string{focus},
+		},
+		{
+			name:    "unpinned old node",
+			files:   map[string]string{"package.json": `{"name": "app"}`, ".yarnrc.yml": "nodeLinker: node-modules\n"},
+			node:    "16.20.2",
+			install: []string{"set version 3.8.7", "install"},
+			prune:   []string{"yarn plugin import workspace-tools", focus},
+		},
+	}
+	for _, test := range tests {
+		dir := writeFiles(t, test.files)
+
+		meta := &Metadata{Version: test.node, Prune: []string{focus}}
+		tool := &Tool{Install: []string{"install"}}
+		err := (&NodePack{WorkDir: dir}).yarnBerrySetup(meta, tool)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.install, tool.Install, test.name)
+		assert.Equal(t, test.prune, meta.Prune, test.name)
+	}
+}
@@ -3,17 +3,23 @@ package pack
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/ake-persson/mapslice-json"
+	"github.com/aquasecurity/go-version/pkg/version"
 )
 
 type PhpPack struct {
 	WorkDir string
+	Variant string
+	Ini     map[string]string
+	Rules   *Rules
 }
 
 func (p *PhpPack) Detect() bool {
@@ -27,19 +33,39 @@ func (p *PhpPack) Metadata() *Metadata {
 		User:    "www-data",
 		Variant: "apache",
 	}
-	meta.Depends = append(meta.Depends, &Depend{
-		Args: []string{
+	root := filepath.Join(meta.Path, p.webroot())
+	if p.Variant == "fpm" {
+		meta.Variant = "fpm"
+		meta.Packages = []string{"gettext-base", "nginx"}
+		args := []string{
 			"ln -s php.ini-production $PHP_INI_DIR/php.ini",
-			"a2enmod rewrite && chown www-data:www-data /var/www",
-			"echo 'ServerName localhost' >> /etc/apache2/apache2.conf",
-			"echo 'DocumentRoot ${APACHE_ROOT}' >> /etc/apache2/apache2.conf",
-			"echo ': ${PORT:=3000}\\nexport PORT' >> /etc/apache2/envvars",
-			"sed -i 's/^Listen.*/Listen ${PORT}/' /etc/apache2/ports.conf",
-		},
-	})
-	meta.Env = map[string]string{
-		"APACHE_ROOT": filepath.Join(meta.Path, p.webroot()) + "/",
+			"ln -sf /dev/stdout /var/log/nginx/access.log",
+			"ln -sf /dev/stderr /var/log/nginx/error.log",
+			"chown -R www-data:www-data /var/www /var/log/nginx",
+		}
+		args = append(args, phpEcho(fmt.Sprintf(phpNginxConf, root), "/etc/nginx/jet.conf.template")...)
+		args = append(args, phpEcho(phpFpmConf, "/usr/local/etc/php-fpm.d/zz-jet.conf")...)
+		args = append(args, phpEcho(phpFpmScript, phpFpmCommand)...)
+		meta.Depends = append(meta.Depends, &Depend{
+			Args: append(args, "chmod +x "+phpFpmCommand),
+		})
+	} else {
+		meta.Depends = append(meta.Depends, &Depend{
+			Args: []string{
+				"ln -s php.ini-production $PHP_INI_DIR/php.ini",
+				"a2enmod rewrite && chown www-data:www-data /var/www",
+				"echo 'ServerName localhost' >> /etc/apache2/apache2.conf",
+				"echo 'DocumentRoot ${APACHE_ROOT}' >> /etc/apache2/apache2.conf",
+				"sed -i 's/^Listen.*/Listen ${PORT}/' /etc/apache2/ports.conf",
+			},
+		})
+		meta.Env = map[string]string{
+			"APACHE_ROOT": root + "/",
+		}
 	}
+	meta.Depends = append(meta.Depends, &Depend{
+		Args: append([]string{"docker-php-ext-enable opcache"}, phpEcho(p.ini(meta.Path), "$PHP_INI_DIR/conf.d/zz-jet.ini")...),
+	})
 
 	if fileExists(p.WorkDir, "composer.json") {
 		conf, core, load, pecl, pkgs := p.extensions()
@@ -63,6 +89,7 @@ func (p *PhpPack) Metadata() *Metadata {
 				Name: "pecl install",
 				Args: pecl,
 				List: true,
+				Hook: p.peclVersions,
 			})
 			meta.Depends = append(meta.Depends, &Depend{
 				Name: "docker-php-ext-enable",
@@ -83,20 +110,148 @@ func (p *PhpPack) Metadata() *Metadata {
 	return meta
 }
 
+func (p *PhpPack) frameworkSetup(meta *Metadata, dirs ...string) {
+	meta.Install = append(meta.Install,
+		"composer dump-autoload --optimize --no-dev",
+		"mkdir -p "+strings.Join(dirs, " "),
+		"chmod -R ug+rwX "+strings.Join(dirs, " "),
+	)
+	if !fileExists(p.WorkDir, "package.json") {
+		return
+	}
+
+	meta.Tools = append(meta.Tools, &Tool{
+		Name:  "node",
+		Owner: "nodejs",
+	})
+	if fileExists(p.WorkDir, "yarn.lock") {
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:     "yarn",
+			Download: "corepack enable",
+			Files:    []string{"package.json", "yarn.lock"},
+			Install:  []string{"install --frozen-lockfile"},
+		})
+	} else if fileExists(p.WorkDir, "package-lock.json") {
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:    "npm",
+			Files:   []string{"package.json", "package-lock.json"},
+			Install: []string{"ci"},
+		})
+	} else {
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:    "npm",
+			Files:   []string{"package.json"},
+			Install: []string{"install"},
+		})
+	}
+
+	if (&NodePack{WorkDir: p.WorkDir}).scripts()["build"] {
+		meta.Install = append(meta.Install, meta.Tools[len(meta.Tools)-1].Name+" run build")
+	}
+}
+
+func iniValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "On"
+		}
+		return "Off"
+	}
+	return fmt.Sprint(value)
+}
+
+func (p *PhpPack) ini(path string) string {
+	ini := map[string]string{}
+	for key, value := range p.Rules.orDefault().Php.Ini {
+		ini[key] = value
+	}
+
+	for _, file := range []string{"preload.php", "config/preload.php"} {
+		if fileExists(p.WorkDir, file) {
+			ini["opcache.preload"] = filepath.Join(path, file)
+			ini["opcache.preload_user"] = "www-data"
+			break
+		}
+	}
+
+	conf := struct {
+		Extra struct {
+			Jet struct {
+				PhpIni map[string]interface{} `json:"php_ini"`
+			} `json:"jet"`
+		} `json:"extra"`
+	}{}
+	if b, err := fileRead(p.WorkDir, "composer.json"); err == nil {
+		json.Unmarshal(b, &conf)
+	}
+	for key, value := range conf.Extra.Jet.PhpIni {
+		ini[key] = iniValue(value)
+	}
+	for key, value := range p.Ini {
+		ini[key] = value
+	}
+
+	keys := []string{}
+	for key := range ini {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, key := range keys {
+		lines = append(lines, key+" = "+ini[key])
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (p *PhpPack) Name() string {
 	return "php"
 }
 
 func (p *PhpPack) Command() (string, error) {
+	if p.Variant == "fpm" {
+		return phpFpmCommand, nil
+	}
 	return "", nil
 }
 
 func (p *PhpPack) Version() (string, error) {
+	if _, version := toolVersion(p.WorkDir, "php"); version != "" {
+		return version, nil
+	}
+
 	requires := p.requires()
 	version := phpPipe.Split(requires["php"], -1)
 	return strings.Join(version, "||"), nil
 }
 
+func (p *PhpPack) Dependencies() []*Component {
+	b, err := fileRead(p.WorkDir, "composer.lock")
+	if err != nil {
+		return nil
+	}
+
+	lock := composerLock{}
+	if err = json.Unmarshal(b, &lock); err != nil {
+		return nil
+	}
+
+	var deps []*Component
+	for _, pkg := range lock.Packages {
+		version := strings.TrimPrefix(pkg.Version, "v")
+		deps = append(deps, &Component{
+			Name:    pkg.Name,
+			Version: version,
+			Type:    "library",
+			Purl:    purl("composer", pkg.Name, version),
+		})
+	}
+	return deps
+}
+
 func (p *PhpPack) extensions() ([]string, []string, []string, []string, []string) {
 	requires := p.requires()
 	var exts []string
@@ -112,22 +267,16 @@ func (p *PhpPack) extensions() ([]string, []string, []string, []string, []string
 	seen := map[string]bool{}
 	for _, ext := range exts {
 		var args []string
-		var ok bool
-		if args, ok = phpCoreExts[ext]; ok {
+		if info, ok := p.Rules.orDefault().Php.Core[ext]; ok {
 			core = append(core, ext)
-			if len(args) > 1 {
-				ext += " " + args[1]
-				conf = append(conf, ext)
+			if info.Configure != "" {
+				conf = append(conf, ext+" "+info.Configure)
 			}
-		} else if args, ok = phpPeclExts[ext]; ok {
+			args = info.Packages
+		} else if info, ok := p.Rules.orDefault().Php.Pecl[ext]; ok {
 			load = append(load, ext)
-			if len(args) > 1 {
-				ext += "-" + args[1]
-			}
 			pecl = append(pecl, ext)
-		}
-		if len(args) > 0 {
-			args = strings.Split(args[0], ",")
+			args = info.Packages
 		}
 		for _, pkg := range args {
 			if !seen[pkg] {
@@ -140,12 +289,100 @@ func (p *PhpPack) extensions() ([]string, []string, []string, []string, []string
 	return conf, core, load, pecl, pkgs
 }
 
+func (p *PhpPack) peclVersions(meta *Metadata, depend *Depend) error {
+	php, err := version.Parse(strings.Split(meta.Version, "-")[0])
+	if err != nil {
+		return err
+	}
+
+	requires := p.requires()
+	for i, ext := range depend.Args {
+		var constraints []string
+		if info := p.Rules.orDefault().Php.Pecl[ext]; info != nil && info.Version != "" {
+			constraints = append(constraints, info.Version)
+		}
+		if require := requires["ext-"+ext]; require != "" && require != "*" {
+			constraints = append(constraints, strings.Join(phpPipe.Split(require, -1), "||"))
+		}
+
+		release, err := peclVersion(ext, php, constraints)
+		if err != nil {
+			return err
+		}
+		depend.Args[i] = ext + "-" + release
+	}
+	return nil
+}
+
+func peclVersion(name string, php version.Version, constraints []string) (string, error) {
+	checks := []version.Constraints{}
+	for _, constraint := range constraints {
+		c, err := version.NewConstraints(constraint)
+		if err != nil {
+			return "", err
+		}
+		checks = append(checks, c)
+	}
+
+	releases, err := getPeclReleases(name)
+	if err != nil {
+		return "", err
+	}
+
+	type candidate struct {
+		release string
+		version version.Version
+	}
+	candidates := []candidate{}
+	for _, release := range releases {
+		v, err := version.Parse(release.Version)
+		if err != nil || release.Stability != "stable" {
+			continue
+		}
+
+		matched := true
+		for _, check := range checks {
+			matched = matched && check.Check(v)
+		}
+		if matched {
+			candidates = append(candidates, candidate{release.Version, v})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].version.GreaterThan(candidates[j].version)
+	})
+
+	for _, c := range candidates {
+		min, max, err := getPeclPhp(name, c.release)
+		if err != nil {
+			return "", err
+		}
+		if min != "" {
+			if v, err := version.Parse(min); err == nil && php.LessThan(v) {
+				continue
+			}
+		}
+		if max != "" {
+			if v, err := version.Parse(max); err == nil && php.GreaterThan(v) {
+				continue
+			}
+		}
+		return c.release, nil
+	}
+
+	if len(constraints) > 0 {
+		return "", fmt.Errorf("Unknown pecl %s version %s for php %s", name, strings.Join(constraints, ","), php)
+	}
+	return "", fmt.Errorf("Unknown pecl %s version for php %s", name, php)
+}
+
 type composerLock struct {
 	Packages []composerPackage `json:"packages"`
 }
 
 type composerPackage struct {
 	Name    string            `json:"name"`
+	Version string            `json:"version"`
 	Require mapslice.MapSlice `json:"require"`
 }
 
@@ -240,72 +477,84 @@ func (p *PhpPack) webroot() string {
 	return filepath.Dir(paths[0])
 }
 
-var (
-	phpLayout = regexp.MustCompile(`^require.*index\.php`)
-	phpPipe   = regexp.MustCompile(`\|+`)
-)
+func phpEcho(conf, file string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(conf), "\n") {
+		lines = append(lines, "echo "+shellQuote(line)+" >> "+file)
+	}
+	return lines
+}
 
-const (
-	phpImap = "--with-kerberos --with-imap-ssl"
-	phpODBC = "--with-pdo-odbc=unixODBC,/usr"
-)
+const phpFpmCommand = "/usr/local/bin/jet-fpm"
 
-var phpCoreExts = map[string][]string{
-	"bz2":          []string{"libbz2-dev"},
-	"curl":         []string{"libcurl4-openssl-dev"},
-	"dba":          []string{},
-	"enchant":      []string{"libenchant-*dev"},
-	"exif":         []string{},
-	"fileinfo":     []string{},
-	"ftp":          []string{"libssl-dev"},
-	"gd":           []string{"libpng-dev"},
-	"gettext":      []string{},
-	"gmp":          []string{"libgmp-dev"},
-	"imap":         []string{"libc-client-dev,libkrb5-dev", phpImap},
-	"intl":         []string{"libicu-dev"},
-	"ldap":         []string{"libldap2-dev"},
-	"mbstring":     []string{"libonig-dev"},
-	"mysqli":       []string{},
-	"pcntl":        []string{},
-	"pdo":          []string{},
-	"pdo_firebird": []string{"firebird-dev"},
-	"pdo_mysql":    []string{},
-	"pdo_odbc":     []string{"unixodbc-dev", phpODBC},
-	"pdo_pgsql":    []string{"libpq-dev"},
-	"pdo_sqlite":   []string{"libsqlite3-dev"},
-	"pgsql":        []string{"libpq-dev"},
-	"pspell":       []string{"libpspell-dev"},
-	"shmop":        []string{},
-	"snmp":         []string{"libsnmp-dev"},
-	"soap":         []string{"libxml2-dev"},
-	"sockets":      []string{},
-	"sysvmsg":      []string{},
-	"sysvsem":      []string{},
-	"sysvshm":      []string{},
-	"tidy":         []string{"libtidy-dev"},
-	"xsl":          []string{"libxslt1-dev"},
-	"zip":          []string{"libzip-dev"},
-}
+// phpFpmScript runs php-fpm and nginx in the foreground and exits as soon as
+// either of them does, so the container stops instead of serving errors.
+const phpFpmScript = `
+#!/bin/sh
+envsubst '${PORT}' < /etc/nginx/jet.conf.template > /tmp/nginx.conf
+php-fpm -F &
+fpm=$!
+nginx -c /tmp/nginx.conf &
+nginx=$!
+trap 'status=0; kill $fpm $nginx 2>/dev/null' INT TERM
+while kill -0 $fpm 2>/dev/null && kill -0 $nginx 2>/dev/null; do
+    sleep 1
+done
+kill $fpm $nginx 2>/dev/null
+wait
+exit ${status:-1}
+`
 
-var phpPeclExts = map[string][]string{
-	"amqp":      []string{"librabbitmq-dev"},
-	"apcu":      []string{},
-	"igbinary":  []string{},
-	"imagick":   []string{"libmagickwand-dev"},
-	"lzf":       []string{},
-	"mailparse": []string{},
-	"maxminddb": []string{"libmaxminddb-dev"},
-	"mcrypt":    []string{"libmcrypt-dev"},
-	"memcached": []string{"libmemcached-dev"},
-	"mongodb":   []string{},
-	"msgpack":   []string{},
-	"oauth":     []string{"libpcre3-dev"},
-	"protobuf":  []string{},
-	"psr":       []string{},
-	"rdkafka":   []string{"librdkafka-dev"},
-	"redis":     []string{},
-	"solr":      []string{"libcurl4-openssl-dev,libxml2-dev"},
-	"stomp":     []string{"libssl-dev"},
-	"yaf":       []string{},
-	"yaml":      []string{"libyaml-dev"},
+const phpNginxConf = `
+pid /tmp/nginx.pid;
+daemon off;
+error_log /dev/stderr;
+events {
+}
+http {
+    include /etc/nginx/mime.types;
+    default_type application/octet-stream;
+    access_log /dev/stdout;
+    client_body_temp_path /tmp/client_body;
+    fastcgi_temp_path /tmp/fastcgi;
+    proxy_temp_path /tmp/proxy;
+    scgi_temp_path /tmp/scgi;
+    uwsgi_temp_path /tmp/uwsgi;
+    server {
+        listen ${PORT};
+        root %s;
+        index index.php index.html;
+        client_max_body_size 32m;
+        location / {
+            try_files $uri $uri/ /index.php?$query_string;
+        }
+        location ~ \.php$ {
+            fastcgi_pass 127.0.0.1:9000;
+            include /etc/nginx/fastcgi_params;
+            fastcgi_param SCRIPT_FILENAME $realpath_root$fastcgi_script_name;
+        }
+        location ~ /\.(?!well-known) {
+            deny all;
+        }
+    }
 }
+`
+
+const phpFpmConf = `
+[www]
+clear_env = no
+catch_workers_output = yes
+pm = dynamic
+pm.max_children = 10
+pm.start_servers = 2
+pm.min_spare_servers = 1
+pm.max_spare_servers = 4
+pm.max_requests = 500
+`
+
+var (
+	peclBoundRegex = regexp.MustCompile(`s:3:"(min|max)";s:\d+:"([^"]+)"`)
+	peclPhpRegex   = regexp.MustCompile(`s:3:"php";a:\d+:\{([^}]*)\}`)
+	phpLayout      = regexp.MustCompile(`^require.*index\.php`)
+	phpPipe        = regexp.MustCompile(`\|+`)
+)
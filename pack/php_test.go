@@ -0,0 +1,136 @@
+package pack
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/go-version/pkg/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhpIni(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{value: "512M", expected: "512M"},
+		{value: 1000000.0, expected: "1000000"},
+		{value: 0.005, expected: "0.005"},
+		{value: true, expected: "On"},
+		{value: false, expected: "Off"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, iniValue(test.value), test.expected)
+	}
+}
+
+func TestPhpFpm(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"index.php": "<?php echo 'hello';\n"})
+	p := &PhpPack{WorkDir: dir, Variant: "fpm"}
+
+	command, err := p.Command()
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/local/bin/jet-fpm", command)
+
+	args := p.Metadata().Depends[0].Args
+	assert.Contains(t, args, "echo 'php-fpm -F &' >> /usr/local/bin/jet-fpm")
+	assert.Contains(t, args, `echo 'trap '\''status=0; kill $fpm $nginx 2>/dev/null'\'' INT TERM' >> /usr/local/bin/jet-fpm`)
+	assert.Equal(t, "chmod +x /usr/local/bin/jet-fpm", args[len(args)-1])
+}
+
+func TestPeclVersion(t *testing.T) {
+	const (
+		releases = `<a><r><v>6.1.0RC1</v><s>beta</s></r><r><v>5.3.7</v><s>stable</s></r>` +
+			`<r><v>6.0.2</v><s>stable</s></r><r><v>4.3.0</v><s>stable</s></r></a>`
+		php80  = `a:1:{s:8:"required";a:1:{s:3:"php";a:2:{s:3:"min";s:5:"7.0.0";s:3:"max";s:5:"8.0.0";}}}`
+		php83  = `a:1:{s:8:"required";a:1:{s:3:"php";a:2:{s:3:"min";s:5:"7.4.0";s:3:"max";s:6:"8.3.99";}}}`
+		php74  = `a:1:{s:8:"required";a:1:{s:3:"php";a:2:{s:3:"min";s:5:"7.0.0";s:3:"max";s:6:"7.4.99";}}}`
+		php74x = `a:1:{s:8:"required";a:1:{s:3:"php";a:1:{s:3:"min";s:5:"7.4.0";}}}`
+		noPhp  = `a:1:{s:8:"required";a:1:{s:4:"pear";a:1:{s:3:"min";s:5:"1.4.0";}}}`
+		allXML = "redis/allreleases.xml"
+	)
+	tests := []struct {
+		name        string
+		php         string
+		constraints []string
+		stub        stubTransport
+		expected    string
+		err         string
+	}{
+		{
+			name:     "newest compatible",
+			php:      "8.3.12",
+			stub:     stubTransport{allXML: releases, "deps.6.0.2.txt": php83},
+			expected: "6.0.2",
+		},
+		{
+			name:     "above max",
+			php:      "8.4.1",
+			stub:     stubTransport{allXML: releases, "deps.6.0.2.txt": php83, "deps.5.3.7.txt": php74x},
+			expected: "5.3.7",
+		},
+		{
+			name:     "below min",
+			php:      "7.2.34",
+			stub:     stubTransport{allXML: releases, "deps.6.0.2.txt": php83, "deps.5.3.7.txt": php80},
+			expected: "5.3.7",
+		},
+		{
+			name:     "at max",
+			php:      "8.0.0",
+			stub:     stubTransport{allXML: releases, "deps.6.0.2.txt": php80},
+			expected: "6.0.2",
+		},
+		{
+			name:        "constraint",
+			php:         "8.3.12",
+			constraints: []string{"^5.3"},
+			stub:        stubTransport{allXML: releases, "deps.5.3.7.txt": php83},
+			expected:    "5.3.7",
+		},
+		{
+			name: "no compatible release",
+			php:  "8.4.1",
+			stub: stubTransport{allXML: releases, "deps.6.0.2.txt": php83, "deps.5.3.7.txt": php80, "deps.4.3.0.txt": php74},
+			err:  "Unknown pecl redis version for php 8.4.1",
+		},
+		{
+			name:        "no release matching constraint",
+			php:         "8.3.12",
+			constraints: []string{"^7.0"},
+			stub:        stubTransport{allXML: releases},
+			err:         "Unknown pecl redis version ^7.0 for php 8.3.12",
+		},
+		{
+			name: "missing deps",
+			php:  "8.3.12",
+			stub: stubTransport{allXML: releases, "deps.6.0.2.txt": ""},
+			err:  "Unknown pecl redis 6.0.2 dependencies",
+		},
+		{
+			name: "malformed deps",
+			php:  "8.3.12",
+			stub: stubTransport{allXML: releases, "deps.6.0.2.txt": noPhp},
+			err:  "Invalid pecl redis 6.0.2 dependencies",
+		},
+		{
+			name: "missing extension",
+			php:  "8.3.12",
+			stub: stubTransport{allXML: ""},
+			err:  "Unknown pecl redis",
+		},
+	}
+	for _, test := range tests {
+		stubHTTP(t, test.stub)
+		php, err := version.Parse(test.php)
+		require.NoError(t, err)
+
+		release, err := peclVersion("redis", php, test.constraints)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, release, test.name)
+	}
+}
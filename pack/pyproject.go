@@ -0,0 +1,130 @@
+package pack
+
+import (
+	"strings"
+)
+
+type pyProject struct {
+	Dependencies   []string
+	Poetry         bool
+	PoetryDepends  map[string]string
+	Project        bool
+	RequiresPython string
+}
+
+func readPyProject(dir string) *pyProject {
+	conf := struct {
+		Project *struct {
+			Dependencies   []string `toml:"dependencies"`
+			RequiresPython string   `toml:"requires-python"`
+		} `toml:"project"`
+		Tool struct {
+			Poetry *struct {
+				Dependencies map[string]interface{} `toml:"dependencies"`
+			} `toml:"poetry"`
+		} `toml:"tool"`
+	}{}
+	if err := fileToml(dir, "pyproject.toml", &conf); err != nil {
+		return nil
+	}
+
+	project := &pyProject{PoetryDepends: map[string]string{}}
+	if conf.Project != nil {
+		project.Project = true
+		project.Dependencies = conf.Project.Dependencies
+		project.RequiresPython = conf.Project.RequiresPython
+	}
+	if poetry := conf.Tool.Poetry; poetry != nil {
+		project.Poetry = true
+		for name, value := range poetry.Dependencies {
+			project.PoetryDepends[strings.ToLower(name)] = tomlVersion(value)
+		}
+	}
+
+	if !project.Project && !project.Poetry {
+		return nil
+	}
+	return project
+}
+
+func (p *pyProject) requirements() map[string]bool {
+	requirements := map[string]bool{}
+	for _, dep := range p.Dependencies {
+		if matches := pinRegex.FindStringSubmatch(dep); len(matches) > 1 {
+			requirements[strings.ToLower(matches[1])] = true
+		}
+	}
+	for name := range p.PoetryDepends {
+		if name != "python" {
+			requirements[name] = true
+		}
+	}
+	return requirements
+}
+
+func (p *pyProject) version() string {
+	if p.RequiresPython != "" {
+		return pyConstraint(p.RequiresPython)
+	}
+	return pyConstraint(p.PoetryDepends["python"])
+}
+
+func pyConstraint(constraint string) string {
+	var parts []string
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, "~="):
+			version := strings.TrimSpace(strings.TrimPrefix(part, "~="))
+			if strings.Count(version, ".") > 1 {
+				part = "~" + version
+			} else {
+				part = "^" + version
+			}
+		case strings.HasPrefix(part, "==="), strings.HasPrefix(part, "=="):
+			part = strings.TrimLeft(part, "=")
+			part = strings.TrimSpace(strings.ReplaceAll(part, ".*", ".x"))
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func pyLockDependencies(dir, name string) []*Component {
+	lock := struct {
+		Packages []struct {
+			Name    string `toml:"name"`
+			Version string `toml:"version"`
+		} `toml:"package"`
+	}{}
+	if err := fileToml(dir, name, &lock); err != nil {
+		return nil
+	}
+
+	var deps []*Component
+	for _, pkg := range lock.Packages {
+		name := strings.ToLower(pkg.Name)
+		deps = append(deps, &Component{
+			Name:    name,
+			Version: pkg.Version,
+			Type:    "library",
+			Purl:    purl("pypi", name, pkg.Version),
+		})
+	}
+	return deps
+}
+
+// tomlVersion reads a version from a string, or from the version key of an
+// inline table like {version = "^3.0", extras = ["dev"]}.
+func tomlVersion(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		version, _ := v["version"].(string)
+		return version
+	}
+	return ""
+}
@@ -10,35 +10,68 @@ import (
 
 type PythonPack struct {
 	WorkDir string
+	Rules   *Rules
 }
 
 func (p *PythonPack) Detect() bool {
 	return fileExists(p.WorkDir, "requirements.txt") ||
 		fileExists(p.WorkDir, "setup.py") ||
 		fileExists(p.WorkDir, "environment.yml") ||
-		fileExists(p.WorkDir, "Pipfile")
+		fileExists(p.WorkDir, "Pipfile") ||
+		fileExists(p.WorkDir, "poetry.lock") ||
+		fileExists(p.WorkDir, "pdm.lock") ||
+		fileExists(p.WorkDir, "uv.lock") ||
+		readPyProject(p.WorkDir) != nil
 }
 
 func (p *PythonPack) Metadata() *Metadata {
 	user := "web"
 	meta := &Metadata{
-		Env: map[string]string{
-			"PATH": "/home/" + user + "/.local/bin:$PATH",
-		},
+		Bin:  []string{"/home/" + user + "/.local/bin"},
+		Env:  map[string]string{},
+		Keep: []string{"/home/" + user + "/.local/"},
 		User: user,
 	}
 	requirements := p.requirements()
-	if !requirements["gunicorn"] {
+	server := "gunicorn"
+	if app, _ := p.application(); app != nil {
+		server = strings.TrimPrefix(pyServer(app.kind, requirements), "gunicorn-")
+	}
+	if !requirements[server] {
 		meta.Tools = append(meta.Tools, &Tool{
 			Name:    "pip",
-			Install: []string{"install gunicorn"},
+			Install: []string{"install " + server},
 		})
 	}
-	if requirements["pylibmc"] {
-		meta.Packages = append(meta.Packages, "libmemcached-dev")
-	}
+	getPackages(meta, p.Rules.orDefault().Python.Packages, packVersions(p))
+	project := readPyProject(p.WorkDir)
 	switch {
 	case fileExists(p.WorkDir, "requirements.txt"):
+	case fileExists(p.WorkDir, "poetry.lock") || project != nil && project.Poetry:
+		install := []string{"export --without-hashes -o requirements.txt"}
+		if !fileExists(p.WorkDir, "poetry.lock") {
+			install = append([]string{"lock"}, install...)
+		}
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:     "poetry",
+			Download: "pip install poetry poetry-plugin-export",
+			Files:    []string{"pyproject.toml", "poetry.lock"},
+			Install:  install,
+		})
+	case fileExists(p.WorkDir, "pdm.lock"):
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:     "pdm",
+			Download: "pip install pdm",
+			Files:    []string{"pyproject.toml", "pdm.lock"},
+			Install:  []string{"export --without-hashes -o requirements.txt"},
+		})
+	case fileExists(p.WorkDir, "uv.lock"):
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:     "uv",
+			Download: "pip install uv",
+			Files:    []string{"pyproject.toml", "uv.lock"},
+			Install:  []string{"export --frozen --no-hashes --no-emit-project -o requirements.txt"},
+		})
 	case fileExists(p.WorkDir, "setup.py"):
 		meta.Tools = append(meta.Tools, &Tool{
 			Name:     "pip-compile",
@@ -47,16 +80,29 @@ func (p *PythonPack) Metadata() *Metadata {
 			Install:  []string{"setup.py"},
 		})
 	case fileExists(p.WorkDir, "environment.yml"):
-		convert := []string{
-			"'.dependencies[]' environment.yml",
-			"sed 's/=/==/;/^python=/d' > requirements.txt",
+		prefix := "/home/" + user + "/conda"
+		meta.Env["CONDA_PREFIX"] = prefix
+		meta.Env["MAMBA_ROOT_PREFIX"] = "/home/" + user + "/micromamba"
+		meta.Bin = append([]string{prefix + "/bin"}, meta.Bin...)
+		meta.Keep = []string{prefix + "/"}
+
+		create := "create -y -p " + prefix + " -f environment.yml"
+		if !fileContains(p.WorkDir, "environment.yml", "channels:") {
+			create = "create -y -p " + prefix + " -c conda-forge -f environment.yml"
 		}
-		meta.Tools = append(meta.Tools, &Tool{
-			Name:    "yq",
-			Owner:   "mikefarah",
+		install := []string{create}
+		if !requirements[server] {
+			install = append(install, "install -y -p "+prefix+" -c conda-forge "+server)
+		}
+		meta.Tools = []*Tool{{
+			Name:    "micromamba",
+			Owner:   "mamba-org",
+			Repo:    "micromamba-releases",
+			Asset:   `^micromamba-linux-64$`,
 			Files:   []string{"environment.yml"},
-			Install: []string{strings.Join(convert, " | ")},
-		})
+			Install: append(install, "clean --all --yes"),
+		}}
+		return meta
 	case fileExists(p.WorkDir, "Pipfile"):
 		meta.Tools = append(meta.Tools, &Tool{
 			Name:     "pipenv",
@@ -64,6 +110,18 @@ func (p *PythonPack) Metadata() *Metadata {
 			Files:    []string{"Pipfile", "Pipfile.lock"},
 			Install:  []string{"lock -r > requirements.txt"},
 		})
+	case project != nil:
+		var deps []string
+		for _, dep := range project.Dependencies {
+			deps = append(deps, shellQuote(dep))
+		}
+		if len(deps) > 0 {
+			meta.Tools = append(meta.Tools, &Tool{
+				Name:    "pip",
+				Install: []string{"install " + strings.Join(deps, " ")},
+			})
+		}
+		return meta
 	}
 	meta.Tools = append(meta.Tools, &Tool{
 		Name:    "pip",
@@ -78,13 +136,47 @@ func (p *PythonPack) Name() string {
 }
 
 func (p *PythonPack) Command() (string, error) {
-	paths, err := fileGlob(p.WorkDir, "**/*.py")
-	if err != nil {
+	app, err := p.application()
+	if err != nil || app == nil {
 		return "", err
 	}
 
-	for _, path := range paths {
-		file, err := os.Open(filepath.Join(p.WorkDir, path))
+	target := app.module + ":" + app.name
+	switch pyServer(app.kind, p.requirements()) {
+	case "daphne":
+		return "daphne -b 0.0.0.0 -p ${PORT} " + target, nil
+	case "hypercorn":
+		return "hypercorn " + target + " --bind 0.0.0.0:${PORT}", nil
+	case "uvicorn":
+		return "uvicorn " + target + " --host 0.0.0.0 --port ${PORT}", nil
+	case "gunicorn-uvicorn":
+		return "gunicorn " + target + " -k uvicorn.workers.UvicornWorker", nil
+	}
+	return "gunicorn " + target, nil
+}
+
+func (p *PythonPack) Version() (string, error) {
+	if b, err := fileRead(p.WorkDir, ".python-version"); err == nil {
+		if version := strings.TrimSpace(string(b)); version != "" {
+			return version, nil
+		}
+	}
+
+	names := []string{"runtime.txt", ".tool-versions", "pyproject.toml", "environment.yml", "Pipfile"}
+	for _, name := range names {
+		if name == ".tool-versions" {
+			if _, version := toolVersion(p.WorkDir, "python"); version != "" {
+				return version, nil
+			}
+			continue
+		} else if name == "pyproject.toml" {
+			if project := readPyProject(p.WorkDir); project != nil && project.version() != "" {
+				return project.version(), nil
+			}
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(p.WorkDir, name))
 		if err != nil {
 			continue
 		}
@@ -93,21 +185,68 @@ func (p *PythonPack) Command() (string, error) {
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
 			line := scanner.Text()
-			matches := pyappRegex.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				path = strings.TrimSuffix(path, filepath.Ext(path))
-				path = strings.ReplaceAll(path, "/", ".")
-				return "gunicorn " + path + ":" + matches[1], nil
+			matches := pythonRegex.FindStringSubmatch(line)
+			if len(matches) > 2 {
+				return matches[2], nil
 			}
 		}
 	}
 	return "", nil
 }
 
-func (p *PythonPack) Version() (string, error) {
-	names := []string{"runtime.txt", "environment.yml", "Pipfile"}
-	for _, name := range names {
-		file, err := os.Open(filepath.Join(p.WorkDir, name))
+func (p *PythonPack) Dependencies() []*Component {
+	for _, name := range []string{"poetry.lock", "pdm.lock", "uv.lock"} {
+		if fileExists(p.WorkDir, name) {
+			return pyLockDependencies(p.WorkDir, name)
+		}
+	}
+
+	file, err := os.Open(filepath.Join(p.WorkDir, "requirements.txt"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var deps []*Component
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+
+		matches := pinRegex.FindStringSubmatch(line)
+		if len(matches) < 3 {
+			continue
+		}
+
+		name := strings.ToLower(matches[1])
+		deps = append(deps, &Component{
+			Name:    name,
+			Version: matches[2],
+			Type:    "library",
+			Purl:    purl("pypi", name, matches[2]),
+		})
+	}
+	return deps
+}
+
+type pyApp struct {
+	module string
+	name   string
+	kind   string
+}
+
+func (p *PythonPack) application() (*pyApp, error) {
+	paths, err := fileGlob(p.WorkDir, "**/*.py")
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := p.requirements()
+	asgi := requirements["channels"] || requirements["daphne"] || requirements["uvicorn"]
+	for _, path := range paths {
+		file, err := os.Open(filepath.Join(p.WorkDir, path))
 		if err != nil {
 			continue
 		}
@@ -116,16 +255,55 @@ func (p *PythonPack) Version() (string, error) {
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
 			line := scanner.Text()
-			matches := pythonRegex.FindStringSubmatch(line)
-			if len(matches) > 2 {
-				return matches[2], nil
+			matches := pyappRegex.FindStringSubmatch(line)
+			if len(matches) < 3 {
+				continue
 			}
+
+			kind := pyappKinds[matches[2]]
+			if kind == "django-asgi" {
+				if !asgi {
+					continue
+				}
+				kind = "asgi"
+			}
+
+			module := strings.TrimSuffix(path, filepath.Ext(path))
+			return &pyApp{
+				module: strings.ReplaceAll(module, "/", "."),
+				name:   matches[1],
+				kind:   kind,
+			}, nil
 		}
 	}
-	return "", nil
+	return nil, nil
+}
+
+func pyServer(kind string, requirements map[string]bool) string {
+	if kind != "asgi" {
+		return "gunicorn"
+	}
+
+	switch {
+	case requirements["gunicorn"] && requirements["uvicorn"]:
+		return "gunicorn-uvicorn"
+	case requirements["uvicorn"]:
+		return "uvicorn"
+	case requirements["hypercorn"]:
+		return "hypercorn"
+	case requirements["daphne"]:
+		return "daphne"
+	}
+	return "uvicorn"
 }
 
 func (p *PythonPack) requirements() map[string]bool {
+	if !fileExists(p.WorkDir, "requirements.txt") {
+		if project := readPyProject(p.WorkDir); project != nil {
+			return project.requirements()
+		}
+	}
+
 	requirements := map[string]bool{}
 	names := []string{"requirements.txt", "setup.py", "environment.yml", "Pipfile"}
 	for _, name := range names {
@@ -140,7 +318,7 @@ func (p *PythonPack) requirements() map[string]bool {
 			line := scanner.Text()
 			matches := pipRegex.FindStringSubmatch(line)
 			if len(matches) > 1 {
-				requirements[matches[1]] = true
+				requirements[strings.ToLower(matches[1])] = true
 			}
 		}
 		break
@@ -148,8 +326,18 @@ func (p *PythonPack) requirements() map[string]bool {
 	return requirements
 }
 
+var pyappKinds = map[string]string{
+	"FastAPI":              "asgi",
+	"Flask":                "wsgi",
+	"ProtocolTypeRouter":   "django-asgi",
+	"Starlette":            "asgi",
+	"get_asgi_application": "django-asgi",
+	"get_wsgi_application": "wsgi",
+}
+
 var (
+	pinRegex    = regexp.MustCompile(`^\s*([a-zA-Z0-9][-\w.]*)\s*(?:\[.*\])?\s*(?:==\s*([^\s;#]+))?`)
 	pipRegex    = regexp.MustCompile(`(?:^|[-'"])\s*([a-zA-Z_][-\w]*)\s*(?:$|[=<>'"[])`)
-	pyappRegex  = regexp.MustCompile(`(\w+)\s*=\s*[\w.]*(Flask|get_wsgi_application)\(`)
+	pyappRegex  = regexp.MustCompile(`(\w+)\s*=\s*[\w.]*(Flask|FastAPI|Starlette|ProtocolTypeRouter|get_wsgi_application|get_asgi_application)\(`)
 	pythonRegex = regexp.MustCompile(`^-?\s*python(_version)?[-=\s'"]*([.x*\d]+)['"]?$`)
 )
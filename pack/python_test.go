@@ -0,0 +1,80 @@
+package pack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPythonDependencies(t *testing.T) {
+	requirements := `--index-url https://pypi.example.com/simple
+-r base.txt
+-e git+https://github.com/example/lib.git#egg=lib
+git+https://github.com/example/other.git
+Django==4.2.7 # web
+requests[socks]==2.31.0
+gunicorn
+`
+	dir := writeFiles(t, map[string]string{"requirements.txt": requirements})
+
+	var names []string
+	for _, dep := range (&PythonPack{WorkDir: dir}).Dependencies() {
+		names = append(names, dep.Name+"@"+dep.Version)
+	}
+	assert.Equal(t, []string{"django@4.2.7", "requests@2.31.0", "gunicorn@"}, names)
+}
+
+func TestPythonProjectDependencies(t *testing.T) {
+	project := `[project]
+name = "app"
+dependencies = ["flask>=3.0", "tomli; python_version < '3.11'"]
+`
+	dir := writeFiles(t, map[string]string{"pyproject.toml": project})
+
+	meta := (&PythonPack{WorkDir: dir}).Metadata()
+	require.NotEmpty(t, meta.Tools)
+	pip := meta.Tools[len(meta.Tools)-1]
+	assert.Equal(t, "pip", pip.Name)
+	assert.Equal(t, []string{`install 'flask>=3.0' 'tomli; python_version < '\''3.11'\'''`}, pip.Install)
+}
+
+func TestReadPyProject(t *testing.T) {
+	project := "[tool.poetry.dependencies]\nlib = { git = \"https://github.com/example/lib.git\" }\n"
+	dir := writeFiles(t, map[string]string{"pyproject.toml": project})
+
+	p := readPyProject(dir)
+	require.NotNil(t, p)
+	assert.Equal(t, map[string]string{"lib": ""}, p.PoetryDepends)
+	assert.False(t, p.Project)
+	assert.True(t, p.Poetry)
+
+	dir = writeFiles(t, map[string]string{"pyproject.toml": "[tool.black]\nline-length = 88\n"})
+	assert.Nil(t, readPyProject(dir))
+}
+
+func TestPyLockDependencies(t *testing.T) {
+	lock := `version = 1
+
+[[package]]
+name = "Flask"
+version = "3.0.0"
+dependencies = [
+    { name = "werkzeug" },
+]
+
+[package.optional-dependencies]
+dotenv = [{ name = "python-dotenv" }]
+
+[[package]]
+name = "werkzeug"
+version = "3.0.1"
+`
+	dir := writeFiles(t, map[string]string{"uv.lock": lock})
+
+	var names []string
+	for _, dep := range pyLockDependencies(dir, "uv.lock") {
+		names = append(names, dep.Purl)
+	}
+	assert.Equal(t, []string{"pkg:pypi/flask@3.0.0", "pkg:pypi/werkzeug@3.0.1"}, names)
+}
@@ -10,6 +10,7 @@ import (
 
 type RubyPack struct {
 	WorkDir string
+	Rules   *Rules
 }
 
 func (r *RubyPack) Detect() bool {
@@ -21,6 +22,8 @@ func (r *RubyPack) Metadata() *Metadata {
 		User: "web",
 	}
 	specs := r.specs()
+	getPackages(meta, r.Rules.orDefault().Ruby.Packages, packVersions(r))
+
 	if _, ok := specs["puma"]; !ok {
 		meta.Tools = append(meta.Tools, &Tool{
 			Name:    "gem",
@@ -28,6 +31,13 @@ func (r *RubyPack) Metadata() *Metadata {
 		})
 	}
 
+	if _, bundler := r.locked(); bundler != "" {
+		meta.Tools = append(meta.Tools, &Tool{
+			Name:    "gem",
+			Install: []string{"install bundler -v " + bundler},
+		})
+	}
+
 	meta.Tools = append(meta.Tools, &Tool{
 		Name:    "bundle",
 		Files:   []string{"Gemfile", "Gemfile.lock"},
@@ -36,7 +46,7 @@ func (r *RubyPack) Metadata() *Metadata {
 
 	yarn := fileExists(r.WorkDir, "yarn.lock")
 	node := yarn
-	for _, name := range []string{"execjs", "webpacker"} {
+	for _, name := range r.Rules.orDefault().Ruby.Node {
 		if _, ok := specs[name]; ok {
 			node = true
 			break
@@ -71,7 +81,7 @@ func (r *RubyPack) Name() string {
 
 func (r *RubyPack) Command() (string, error) {
 	if fileExists(r.WorkDir, "config.ru") {
-		return "puma -p ${PORT-3000}", nil
+		return "puma -p ${PORT}", nil
 	}
 	return "", nil
 }
@@ -81,7 +91,34 @@ func (r *RubyPack) Version() (string, error) {
 	if engine != "" && engine_version != "" {
 		return engine_version, nil
 	}
-	return ruby_version, nil
+
+	if version := r.versionFile(".ruby-version"); version != "" {
+		return version, nil
+	}
+
+	if _, version := toolVersion(r.WorkDir, "ruby"); version != "" {
+		return version, nil
+	}
+
+	if ruby_version != "" {
+		return ruby_version, nil
+	}
+
+	locked, _ := r.locked()
+	return locked, nil
+}
+
+func (r *RubyPack) Dependencies() []*Component {
+	var deps []*Component
+	for name, version := range r.specs() {
+		deps = append(deps, &Component{
+			Name:    name,
+			Version: version,
+			Type:    "library",
+			Purl:    purl("gem", name, version),
+		})
+	}
+	return deps
 }
 
 func (r *RubyPack) specs() map[string]string {
@@ -125,12 +162,58 @@ func (r *RubyPack) version() (string, string, string) {
 				version[match[1]] = replacer.Replace(match[2])
 			}
 		}
+		if match := rubyFileRegex.FindStringSubmatch(line); len(match) > 1 {
+			version["file"] = match[1]
+		}
 		break
 	}
+
+	if version["ruby"] == "" && version["file"] != "" {
+		version["ruby"] = r.versionFile(version["file"])
+	}
 	return version["engine"], version["engine_version"], version["ruby"]
 }
 
+func (r *RubyPack) versionFile(name string) string {
+	b, err := fileRead(r.WorkDir, name)
+	if err != nil {
+		return ""
+	}
+
+	version, _, _ := strings.Cut(strings.TrimSpace(string(b)), "\n")
+	return strings.TrimPrefix(strings.TrimSpace(version), "ruby-")
+}
+
+func (r *RubyPack) locked() (string, string) {
+	file, err := os.Open(filepath.Join(r.WorkDir, "Gemfile.lock"))
+	if err != nil {
+		return "", ""
+	}
+	defer file.Close()
+
+	var ruby, bundler, section string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, " ") {
+			section = strings.TrimSpace(line)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case section == "RUBY VERSION" && len(fields) > 1 && fields[0] == "ruby":
+			ruby = lockedRubyRegex.ReplaceAllString(fields[1], "")
+		case section == "BUNDLED WITH" && len(fields) > 0:
+			bundler = fields[0]
+		}
+	}
+	return ruby, bundler
+}
+
 var (
-	specRegex = regexp.MustCompile(`^\s+([\w-]+)\s\(([\w-.]+)\)`)
-	rubyRegex = regexp.MustCompile(`['"]?([a-z_]+)['"]?[:=>\s]*['"]([a-z]*[^a-z]*\w)['"]`)
+	lockedRubyRegex = regexp.MustCompile(`p\d+$`)
+	specRegex       = regexp.MustCompile(`^\s+([\w-]+)\s\(([\w-.]+)\)`)
+	rubyFileRegex   = regexp.MustCompile(`(?:\bfile:|:file\s*=>)\s*['"]([^'"]+)['"]`)
+	rubyRegex       = regexp.MustCompile(`['"]?([a-z_]+)['"]?[:=>\s]*['"]([a-z]*[^a-z]*\w)['"]`)
 )
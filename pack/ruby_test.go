@@ -0,0 +1,44 @@
+package pack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRubyVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name:     "gemfile",
+			files:    map[string]string{"Gemfile": "source 'https://rubygems.org'\nruby \"3.2.2\"\n"},
+			expected: "3.2.2",
+		},
+		{
+			name: "gemfile file",
+			files: map[string]string{
+				"Gemfile":   "source 'https://rubygems.org'\nruby file: \".ruby-pin\"\n",
+				".ruby-pin": "ruby-3.1.4\n",
+			},
+			expected: "3.1.4",
+		},
+		{
+			name: "lockfile",
+			files: map[string]string{
+				"Gemfile":      "source 'https://rubygems.org'\ngem 'rack'\n",
+				"Gemfile.lock": "GEM\n  specs:\n    rack (3.0.8)\n\nRUBY VERSION\n   ruby 3.0.6p216\n\nBUNDLED WITH\n   2.4.10\n",
+			},
+			expected: "3.0.6",
+		},
+	}
+	for _, test := range tests {
+		dir := writeFiles(t, test.files)
+		version, err := (&RubyPack{WorkDir: dir}).Version()
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, version, test.name)
+	}
+}
@@ -0,0 +1,107 @@
+package pack
+
+import (
+	"embed"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules/*.yml
+var rulesFiles embed.FS
+
+type Rules struct {
+	Debian map[string]map[string]string `yaml:"debian"`
+	Node   struct {
+		Corepack        []*NodeTool `yaml:"corepack"`
+		CorepackBundled string      `yaml:"corepack_bundled"`
+		NpmCI           string      `yaml:"npm_ci"`
+		NpmOmitDev      string      `yaml:"npm_omit_dev"`
+		Pnpm            []*NodeTool `yaml:"pnpm"`
+		Yarn            []*NodeTool `yaml:"yarn"`
+	} `yaml:"node"`
+	Php struct {
+		Core map[string]*PhpExtension `yaml:"core"`
+		Ini  map[string]string        `yaml:"ini"`
+		Pecl map[string]*PhpExtension `yaml:"pecl"`
+	} `yaml:"php"`
+	Python struct {
+		Packages map[string]*SystemPackages `yaml:"packages"`
+	} `yaml:"python"`
+	Ruby struct {
+		Node     []string                   `yaml:"node"`
+		Packages map[string]*SystemPackages `yaml:"packages"`
+	} `yaml:"ruby"`
+}
+
+var (
+	defaultRules     *Rules
+	defaultRulesOnce sync.Once
+)
+
+type NodeTool struct {
+	Node    string `yaml:"node"`
+	Version string `yaml:"version"`
+}
+
+type PhpExtension struct {
+	Configure string   `yaml:"configure"`
+	Packages  []string `yaml:"packages"`
+	Version   string   `yaml:"version"`
+}
+
+func LoadRules(dir string) (*Rules, error) {
+	r := &Rules{}
+	names, err := rulesFiles.ReadDir("rules")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		b, err := rulesFiles.ReadFile(path.Join("rules", name.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err = yaml.Unmarshal(b, r); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir == "" {
+		return r, nil
+	}
+
+	files := []string{dir}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		files, _ = filepath.Glob(filepath.Join(dir, "*.y*ml"))
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err = yaml.Unmarshal(b, r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Rules) orDefault() *Rules {
+	if r != nil {
+		return r
+	}
+	defaultRulesOnce.Do(func() {
+		defaultRules, _ = LoadRules("")
+	})
+	return defaultRules
+}
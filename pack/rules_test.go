@@ -0,0 +1,59 @@
+package pack
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRules(t *testing.T) {
+	override := `php:
+  pecl:
+    redis:
+      version: 5.3.7
+python:
+  packages:
+    psycopg2:
+      run: [libpq5]
+    mylib:
+      build: [libfoo-dev]
+`
+	dir := writeFiles(t, map[string]string{"rules.yml": override})
+
+	rules, err := LoadRules(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, "--with-kerberos --with-imap-ssl", rules.Php.Core["imap"].Configure)
+	assert.Equal(t, "5.3.7", rules.Php.Pecl["redis"].Version)
+	assert.Equal(t, []string{"libpq5"}, rules.Python.Packages["psycopg2"].Run)
+	assert.Empty(t, rules.Python.Packages["psycopg2"].Build)
+	assert.Equal(t, []string{"libfoo-dev"}, rules.Python.Packages["mylib"].Build)
+	assert.Equal(t, []string{"libxml2-dev", "libxslt1-dev"}, rules.Python.Packages["lxml"].Build)
+	assert.Equal(t, []string{"execjs", "webpacker"}, rules.Ruby.Node)
+	assert.Equal(t, "^8.12 || >=10.3", rules.Node.NpmCI)
+}
+
+func TestLoadRulesIsolated(t *testing.T) {
+	override := "php:\n  pecl:\n    redis:\n      version: 5.3.7\n"
+	dir := writeFiles(t, map[string]string{"rules.yml": override})
+
+	_, err := LoadRules(dir)
+	require.NoError(t, err)
+	_, err = LoadRules(filepath.Join(dir, "missing.yml"))
+	require.Error(t, err)
+
+	rules, err := LoadRules("")
+	require.NoError(t, err)
+	assert.Empty(t, rules.Php.Pecl["redis"].Version)
+	assert.NotEmpty(t, rules.Python.Packages["psycopg2"].Build)
+}
+
+func TestRulesDefault(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"requirements.txt": "psycopg2==2.9.9\n"})
+
+	meta := (&PythonPack{WorkDir: dir}).Metadata()
+	assert.Contains(t, meta.BuildPackages, "libpq-dev")
+	assert.Contains(t, meta.Packages, "libpq5")
+}
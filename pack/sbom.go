@@ -0,0 +1,231 @@
+package pack
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SBOMCycloneDX = "cyclonedx"
+	SBOMSPDX      = "spdx"
+)
+
+var randReader = rand.Reader
+
+type Component struct {
+	Name     string
+	Version  string
+	Type     string
+	Purl     string
+	Download string
+}
+
+type dependencyLister interface {
+	Dependencies() []*Component
+}
+
+func (b *Buildpack) Components() []*Component {
+	meta := b.Metadata
+	components := []*Component{{
+		Name:    meta.Name,
+		Version: meta.Version,
+		Type:    "container",
+		Purl:    purl("docker", "library/"+meta.Name, meta.Version),
+	}}
+
+	for _, pkg := range meta.Packages {
+		components = append(components, &Component{
+			Name: pkg,
+			Type: "library",
+			Purl: purl("deb", "debian/"+pkg, ""),
+		})
+	}
+
+	for _, tool := range meta.Tools {
+		if tool.Name == "" || !strings.HasPrefix(tool.Download, "http") {
+			continue
+		}
+		components = append(components, &Component{
+			Name:     tool.Name,
+			Type:     "application",
+			Download: tool.Download,
+		})
+	}
+
+	if lister, ok := b.pack.(dependencyLister); ok {
+		deps := lister.Dependencies()
+		sort.Slice(deps, func(i, j int) bool {
+			return deps[i].Purl < deps[j].Purl
+		})
+		components = append(components, deps...)
+	}
+	return components
+}
+
+func (b *Buildpack) SBOM(name, format string) ([]byte, error) {
+	components := b.Components()
+	switch format {
+	case SBOMCycloneDX:
+		return cyclonedxDocument(name, components)
+	case SBOMSPDX:
+		return spdxDocument(name, components)
+	}
+	return nil, fmt.Errorf("Unknown SBOM format %s", format)
+}
+
+func cyclonedxDocument(name string, components []*Component) ([]byte, error) {
+	type cdxComponent struct {
+		Ref     string `json:"bom-ref"`
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+		Purl    string `json:"purl,omitempty"`
+	}
+
+	list := []cdxComponent{}
+	for i, c := range components {
+		list = append(list, cdxComponent{
+			Ref:     fmt.Sprintf("component-%d", i),
+			Type:    c.Type,
+			Name:    c.Name,
+			Version: c.Version,
+			Purl:    c.Purl,
+		})
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + uuid,
+		"version":      1,
+		"metadata": map[string]interface{}{
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"tools":     []map[string]string{{"name": "jet"}},
+			"component": map[string]string{
+				"bom-ref": "image",
+				"type":    "container",
+				"name":    name,
+			},
+		},
+		"components": list,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func spdxDocument(name string, components []*Component) ([]byte, error) {
+	type spdxRef struct {
+		Category string `json:"referenceCategory"`
+		Type     string `json:"referenceType"`
+		Locator  string `json:"referenceLocator"`
+	}
+
+	type spdxPackage struct {
+		ID               string    `json:"SPDXID"`
+		Name             string    `json:"name"`
+		Version          string    `json:"versionInfo,omitempty"`
+		DownloadLocation string    `json:"downloadLocation"`
+		FilesAnalyzed    bool      `json:"filesAnalyzed"`
+		ExternalRefs     []spdxRef `json:"externalRefs,omitempty"`
+	}
+
+	type spdxRelationship struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	}
+
+	packages := []spdxPackage{{
+		ID:               "SPDXRef-Image",
+		Name:             name,
+		DownloadLocation: "NOASSERTION",
+	}}
+	relationships := []spdxRelationship{{
+		Element: "SPDXRef-DOCUMENT",
+		Type:    "DESCRIBES",
+		Related: "SPDXRef-Image",
+	}}
+
+	for i, c := range components {
+		pkg := spdxPackage{
+			ID:               fmt.Sprintf("SPDXRef-Package-%d", i),
+			Name:             c.Name,
+			Version:          c.Version,
+			DownloadLocation: "NOASSERTION",
+		}
+		if c.Download != "" {
+			pkg.DownloadLocation = c.Download
+		}
+		if c.Purl != "" {
+			pkg.ExternalRefs = []spdxRef{{
+				Category: "PACKAGE-MANAGER",
+				Type:     "purl",
+				Locator:  c.Purl,
+			}}
+		}
+		packages = append(packages, pkg)
+
+		relationship := "CONTAINS"
+		if c.Type == "container" {
+			relationship = "DESCENDANT_OF"
+		}
+		relationships = append(relationships, spdxRelationship{
+			Element: "SPDXRef-Image",
+			Type:    relationship,
+			Related: pkg.ID,
+		})
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              name,
+		"documentNamespace": "https://lade.io/spdx/" + url.PathEscape(name) + "-" + uuid,
+		"creationInfo": map[string]interface{}{
+			"created":  time.Now().UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: jet"},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(randReader, b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func purl(kind, name, version string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(url.PathEscape(part), "@", "%40")
+	}
+
+	p := "pkg:" + kind + "/" + strings.Join(parts, "/")
+	if version != "" {
+		p += "@" + url.PathEscape(version)
+	}
+	return p
+}
@@ -0,0 +1,77 @@
+package pack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurl(t *testing.T) {
+	tests := []struct {
+		kind     string
+		name     string
+		version  string
+		expected string
+	}{
+		{"docker", "library/node", "20.9.0", "pkg:docker/library/node@20.9.0"},
+		{"deb", "debian/libpq5", "", "pkg:deb/debian/libpq5"},
+		{"golang", "github.com/gin-gonic/gin", "v1.9.1", "pkg:golang/github.com/gin-gonic/gin@v1.9.1"},
+		{"npm", "express", "4.18.2", "pkg:npm/express@4.18.2"},
+		{"npm", "@babel/core", "7.22.5", "pkg:npm/%40babel/core@7.22.5"},
+		{"composer", "laravel/framework", "v10.0.0", "pkg:composer/laravel/framework@v10.0.0"},
+		{"pypi", "django", "4.2.7", "pkg:pypi/django@4.2.7"},
+		{"gem", "rails", "7.1.2", "pkg:gem/rails@7.1.2"},
+		{"pypi", "my package", "1.0 beta", "pkg:pypi/my%20package@1.0%20beta"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, purl(test.kind, test.name, test.version), test.name)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	bp := &Buildpack{
+		Metadata: &Metadata{
+			Name:     "node",
+			Version:  "20.9.0",
+			Packages: []string{"libpq5"},
+			Tools: []*Tool{
+				{Name: "yarn", Download: "corepack enable"},
+				{Name: "jq", Download: "https://example.com/jq-linux-amd64"},
+			},
+		},
+		pack: &NodePack{WorkDir: t.TempDir()},
+	}
+
+	components := bp.Components()
+	require.Len(t, components, 3)
+	assert.Equal(t, "pkg:docker/library/node@20.9.0", components[0].Purl)
+	assert.Equal(t, "pkg:deb/debian/libpq5", components[1].Purl)
+	assert.Equal(t, &Component{
+		Name:     "jq",
+		Type:     "application",
+		Download: "https://example.com/jq-linux-amd64",
+	}, components[2])
+
+	_, err := bp.SBOM("app", "swid")
+	assert.EqualError(t, err, "Unknown SBOM format swid")
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("entropy unavailable")
+}
+
+func TestSBOMRandError(t *testing.T) {
+	reader := randReader
+	randReader = failingReader{}
+	t.Cleanup(func() { randReader = reader })
+
+	bp := &Buildpack{Metadata: &Metadata{Name: "node", Version: "20.9.0"}, pack: &NodePack{WorkDir: t.TempDir()}}
+	for _, format := range []string{SBOMCycloneDX, SBOMSPDX} {
+		_, err := bp.SBOM("app", format)
+		assert.EqualError(t, err, "entropy unavailable", format)
+	}
+}
@@ -0,0 +1,99 @@
+package pack
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var toolFiles = []string{"mise.toml", ".mise.toml", ".tool-versions"}
+
+var toolNames = map[string][]string{
+	"golang": {"go", "golang"},
+	"node":   {"node", "nodejs"},
+	"php":    {"php"},
+	"python": {"python"},
+	"ruby":   {"ruby"},
+}
+
+func toolVersion(workDir, name string) (string, string) {
+	names := map[string]bool{}
+	for _, alias := range toolNames[name] {
+		names[alias] = true
+		names["core:"+alias] = true
+	}
+
+	for _, file := range toolFiles {
+		var versions []string
+		if file == ".tool-versions" {
+			versions = asdfVersions(workDir, file, names)
+		} else {
+			versions = miseVersions(workDir, file, names)
+		}
+
+		for _, version := range versions {
+			version = strings.TrimPrefix(version, "prefix:")
+			switch {
+			case version == "", version == "latest", version == "system":
+			case strings.HasPrefix(version, "ref:"), strings.HasPrefix(version, "path:"):
+			default:
+				return file, version
+			}
+		}
+	}
+	return "", ""
+}
+
+func asdfVersions(workDir, file string, names map[string]bool) []string {
+	f, err := os.Open(filepath.Join(workDir, file))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) > 1 && names[fields[0]] {
+			return fields[1:]
+		}
+	}
+	return nil
+}
+
+func miseVersions(workDir, file string, names map[string]bool) []string {
+	conf := struct {
+		Tools map[string]interface{} `toml:"tools"`
+	}{}
+	if err := fileToml(workDir, file, &conf); err != nil {
+		return nil
+	}
+
+	keys := []string{}
+	for key := range conf.Tools {
+		if names[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	value := conf.Tools[keys[0]]
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	var versions []string
+	for _, value := range values {
+		if version := tomlVersion(value); version != "" {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
@@ -0,0 +1,57 @@
+package pack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		file     string
+		expected string
+	}{
+		{
+			name:     "mise string",
+			files:    map[string]string{"mise.toml": "[tools]\nnode = \"20.9.0\" # lts\n"},
+			file:     "mise.toml",
+			expected: "20.9.0",
+		},
+		{
+			name:     "mise array",
+			files:    map[string]string{".mise.toml": "[tools]\nnode = [\n  \"latest\",\n  \"18\",\n]\n"},
+			file:     ".mise.toml",
+			expected: "18",
+		},
+		{
+			name:     "mise table",
+			files:    map[string]string{"mise.toml": "[tools.nodejs]\nversion = \"prefix:20\"\npostinstall = \"corepack enable\"\n"},
+			file:     "mise.toml",
+			expected: "20",
+		},
+		{
+			name:     "mise inline table",
+			files:    map[string]string{"mise.toml": "[env]\nNODE_ENV = \"production\"\n\n[tools]\n\"core:node\" = { version = \"22\" }\n"},
+			file:     "mise.toml",
+			expected: "22",
+		},
+		{
+			name:     "asdf",
+			files:    map[string]string{"mise.toml": "[tools]\npython = \"3.12\"\n", ".tool-versions": "nodejs 18.18.2 # lts\n"},
+			file:     ".tool-versions",
+			expected: "18.18.2",
+		},
+		{
+			name:  "invalid mise",
+			files: map[string]string{"mise.toml": "[tools]\nnode = 20.9.0\n"},
+		},
+	}
+	for _, test := range tests {
+		dir := writeFiles(t, test.files)
+		file, version := toolVersion(dir, "node")
+		assert.Equal(t, test.file, file, test.name)
+		assert.Equal(t, test.expected, version, test.name)
+	}
+}
@@ -0,0 +1,12 @@
+var express = require("express");
+var greeting = require("greeting");
+var app = express();
+
+app.get("/", function(req, res) {
+  res.send(greeting);
+});
+
+var port = process.env.PORT || 3000;
+app.listen(port, function() {
+  console.log("Listening on " + port);
+});
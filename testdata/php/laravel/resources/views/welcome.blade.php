@@ -0,0 +1,9 @@
+<!DOCTYPE html>
+<html>
+    <head>
+        <title>{{ config('app.name') }}</title>
+    </head>
+    <body>
+        hello, world
+    </body>
+</html>
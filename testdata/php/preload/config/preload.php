@@ -0,0 +1,3 @@
+<?php
+
+opcache_compile_file(__DIR__.'/../public/index.php');
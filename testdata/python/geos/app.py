@@ -0,0 +1,9 @@
+from flask import Flask
+from shapely.geometry import Point
+
+app = Flask(__name__)
+
+
+@app.route("/")
+def index():
+    return str(Point(0, 0).buffer(1).area)